@@ -1,36 +1,114 @@
 package async
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// TypedFuture is the typed result of an async function started with Run
+type TypedFuture[T any] interface {
+	// Await blocks until the function returns or ctx is done
+	Await(ctx context.Context) (T, error)
+	// Done is closed when the function has returned
+	Done() <-chan struct{}
+	// Cancel cancels the context passed to the function
+	Cancel()
+}
+
+// PanicError is returned by Await when the async function panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("async: function panicked: %v", e.Value)
+}
+
+type future[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	result T
+	err    error
+}
+
+func (f *future[T]) Await(ctx context.Context) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case <-f.done:
+		return f.result, f.err
+	}
+}
+
+func (f *future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *future[T]) Cancel() {
+	f.cancel()
+}
+
+// Run executes f in a new goroutine with a child context of ctx.
+// A panic inside f is recovered and returned from Await as a *PanicError.
+func Run[T any](ctx context.Context, f func(ctx context.Context) (T, error)) TypedFuture[T] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	fut := &future[T]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	go fut.run(runCtx, f)
+
+	return fut
+}
+
+// run calls f, stores its outcome and marks the future done
+func (f *future[T]) run(ctx context.Context, fn func(ctx context.Context) (T, error)) {
+	defer close(f.done)
+	defer f.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	f.result, f.err = fn(ctx)
+}
 
 // Future interface has the method signature for await
 type Future interface {
 	Await() interface{}
 }
 
-type future struct {
-	await func(ctx context.Context) interface{}
+type untypedFuture struct {
+	future TypedFuture[interface{}]
 }
 
-func (f future) Await() interface{} {
-	return f.await(context.Background())
+// Await returns the result of the function. A panic of the function is raised again here, as a *PanicError
+// holding the original value and stack, so that it is not mistaken for a nil result.
+func (f untypedFuture) Await() interface{} {
+	result, err := f.future.Await(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	return result
 }
 
-// Exec executes the async function
+// Exec executes the async function. Use Run for a typed result and errors reported separately.
 func Exec(f func() interface{}) Future {
-	var result interface{}
-	c := make(chan struct{})
-	go func() {
-		defer close(c)
-		result = f()
-	}()
-	return future{
-		await: func(ctx context.Context) interface{} {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-c:
-				return result
-			}
-		},
+	return untypedFuture{
+		future: Run(context.Background(), func(context.Context) (interface{}, error) {
+			return f(), nil
+		}),
 	}
 }
@@ -0,0 +1,232 @@
+package async
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("async: circuit breaker is open")
+
+// CircuitOpenError is returned without calling the guarded function while the breaker is open
+type CircuitOpenError struct {
+	Name string
+	// RetryAfter is when the breaker will let a trial call through
+	RetryAfter time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("async: circuit breaker %q is open until %s", e.Name, e.RetryAfter.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type CircuitState int
+
+const (
+	StateClosed CircuitState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(state))
+	}
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker
+type CircuitBreakerOptions struct {
+	// Name identifies the breaker in errors and callbacks
+	Name string
+	// FailureThreshold is the number of consecutive failures that opens the breaker (default 5)
+	FailureThreshold int
+	// CoolDown is how long the breaker stays open before allowing trial calls (default 30s)
+	CoolDown time.Duration
+	// HalfOpenMaxCalls is the number of successful trial calls needed to close the breaker (default 1)
+	HalfOpenMaxCalls int
+	// TrialTimeout reopens the breaker when its trial calls are all still running after it, so that a hung
+	// trial does not keep it half-open (default CoolDown)
+	TrialTimeout time.Duration
+	// IsFailure decides whether an error counts as a failure (nil counts every error)
+	IsFailure func(err error) bool
+	// OnStateChange is called after every state transition
+	OnStateChange func(name string, from CircuitState, to CircuitState)
+}
+
+// CircuitBreaker fails calls fast after repeated failures.
+// A nil *CircuitBreaker is valid and lets every call through.
+type CircuitBreaker struct {
+	opts CircuitBreakerOptions
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	failures   int
+	successes  int
+	inFlight   int
+	openedAt   time.Time
+	trialAt    time.Time
+}
+
+// NewCircuitBreaker creates a closed breaker
+func NewCircuitBreaker(opts CircuitBreakerOptions) *CircuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = 30 * time.Second
+	}
+	if opts.HalfOpenMaxCalls <= 0 {
+		opts.HalfOpenMaxCalls = 1
+	}
+	if opts.TrialTimeout <= 0 {
+		opts.TrialTimeout = opts.CoolDown
+	}
+
+	return &CircuitBreaker{opts: opts}
+}
+
+// State returns the current state of the breaker
+func (cb *CircuitBreaker) State() CircuitState {
+	if cb == nil {
+		return StateClosed
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.opts.CoolDown {
+		return StateHalfOpen
+	}
+	return cb.state
+}
+
+// Execute calls fn if the breaker allows it and records the outcome
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	_, err := Guard(cb, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+// Guard calls fn through cb, returning a *CircuitOpenError without calling fn while cb is open.
+// A panic in fn is recorded as a failure and propagated.
+func Guard[T any](cb *CircuitBreaker, fn func() (T, error)) (T, error) {
+	if cb == nil {
+		return fn()
+	}
+
+	generation, err := cb.allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	failed := true
+	defer func() {
+		cb.record(generation, failed)
+	}()
+
+	result, err := fn()
+	failed = err != nil && (cb.opts.IsFailure == nil || cb.opts.IsFailure(err))
+	return result, err
+}
+
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+
+	var from, to CircuitState
+	changed := false
+	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.opts.CoolDown {
+		from, to, changed = cb.state, StateHalfOpen, true
+		cb.setState(StateHalfOpen)
+	} else if cb.state == StateHalfOpen && cb.inFlight >= cb.opts.HalfOpenMaxCalls && time.Since(cb.trialAt) >= cb.opts.TrialTimeout {
+		// No trial was let through for TrialTimeout, so every running trial is hung
+		from, to, changed = cb.state, StateOpen, true
+		cb.setState(StateOpen)
+	}
+
+	var err error
+	switch cb.state {
+	case StateOpen:
+		err = &CircuitOpenError{Name: cb.opts.Name, RetryAfter: cb.openedAt.Add(cb.opts.CoolDown)}
+	case StateHalfOpen:
+		if cb.inFlight >= cb.opts.HalfOpenMaxCalls {
+			err = &CircuitOpenError{Name: cb.opts.Name, RetryAfter: time.Now().Add(cb.opts.CoolDown)}
+		} else {
+			cb.inFlight++
+			cb.trialAt = time.Now()
+		}
+	}
+	generation := cb.generation
+
+	cb.mu.Unlock()
+
+	if changed {
+		cb.notify(from, to)
+	}
+	return generation, err
+}
+
+func (cb *CircuitBreaker) record(generation uint64, failed bool) {
+	cb.mu.Lock()
+
+	// Outcome of a call started before the last transition
+	if generation != cb.generation {
+		cb.mu.Unlock()
+		return
+	}
+
+	from := cb.state
+	switch cb.state {
+	case StateClosed:
+		if !failed {
+			cb.failures = 0
+		} else if cb.failures++; cb.failures >= cb.opts.FailureThreshold {
+			cb.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		cb.inFlight--
+		if failed {
+			cb.setState(StateOpen)
+		} else if cb.successes++; cb.successes >= cb.opts.HalfOpenMaxCalls {
+			cb.setState(StateClosed)
+		}
+	}
+	to := cb.state
+
+	cb.mu.Unlock()
+
+	if from != to {
+		cb.notify(from, to)
+	}
+}
+
+// setState must be called with cb.mu held
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	cb.generation++
+	cb.failures = 0
+	cb.successes = 0
+	cb.inFlight = 0
+	if state == StateOpen {
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *CircuitBreaker) notify(from CircuitState, to CircuitState) {
+	if cb.opts.OnStateChange != nil {
+		cb.opts.OnStateChange(cb.opts.Name, from, to)
+	}
+}
@@ -0,0 +1,114 @@
+package async
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNoFutures = errors.New("async: no futures to wait on")
+
+// Result holds the outcome of one future passed to Settled
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+type indexedResult[T any] struct {
+	index int
+	Result[T]
+}
+
+// collect awaits every future in its own goroutine and sends the outcomes on the returned channel
+func collect[T any](ctx context.Context, futures []TypedFuture[T]) <-chan indexedResult[T] {
+	results := make(chan indexedResult[T], len(futures))
+	for i, f := range futures {
+		go func(i int, f TypedFuture[T]) {
+			value, err := f.Await(ctx)
+			results <- indexedResult[T]{i, Result[T]{value, err}}
+		}(i, f)
+	}
+	return results
+}
+
+func cancelAll[T any](futures []TypedFuture[T]) {
+	for _, f := range futures {
+		f.Cancel()
+	}
+}
+
+// All waits for every future and returns their values in order.
+// On the first error the remaining futures are cancelled and that error is returned.
+func All[T any](ctx context.Context, futures ...TypedFuture[T]) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	values := make([]T, len(futures))
+	results := collect(ctx, futures)
+	for range futures {
+		r := <-results
+		if r.Err != nil {
+			cancelAll(futures)
+			return nil, r.Err
+		}
+		values[r.index] = r.Value
+	}
+
+	return values, nil
+}
+
+// Any returns the value of the first future that succeeds and cancels the others.
+// If every future fails, the joined errors are returned.
+func Any[T any](ctx context.Context, futures ...TypedFuture[T]) (T, error) {
+	var zero T
+	if len(futures) == 0 {
+		return zero, ErrNoFutures
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	errs := make([]error, len(futures))
+	results := collect(ctx, futures)
+	for range futures {
+		r := <-results
+		if r.Err == nil {
+			cancelAll(futures)
+			return r.Value, nil
+		}
+		errs[r.index] = r.Err
+	}
+
+	return zero, errors.Join(errs...)
+}
+
+// Race returns the outcome of the first future to finish, successful or not, and cancels the others
+func Race[T any](ctx context.Context, futures ...TypedFuture[T]) (T, error) {
+	if len(futures) == 0 {
+		var zero T
+		return zero, ErrNoFutures
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	r := <-collect(ctx, futures)
+	cancelAll(futures)
+	return r.Value, r.Err
+}
+
+// Settled waits for every future and returns each outcome in order, without cancelling on errors
+func Settled[T any](ctx context.Context, futures ...TypedFuture[T]) []Result[T] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	settled := make([]Result[T], len(futures))
+	results := collect(ctx, futures)
+	for range futures {
+		r := <-results
+		settled[r.index] = r.Result
+	}
+
+	return settled
+}
@@ -0,0 +1,184 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrPoolClosed = errors.New("async: pool is shut down")
+var ErrPoolQueueFull = errors.New("async: pool queue is full")
+
+// PoolOptions configures a Pool
+type PoolOptions struct {
+	// Workers is the number of goroutines running tasks (default 1)
+	Workers int
+	// QueueSize is the number of submitted tasks waiting for a worker (default 0, unbuffered)
+	QueueSize int
+	// RejectWhenFull makes Submit return ErrPoolQueueFull instead of blocking when the queue is full
+	RejectWhenFull bool
+	// OnQueueDepth is called with the queue length after each submit and dequeue
+	OnQueueDepth func(depth int)
+	// OnTaskDone is called after each task with its time spent queued, its run time and its error
+	OnTaskDone func(wait time.Duration, run time.Duration, err error)
+}
+
+// Pool runs submitted tasks on a fixed number of workers
+type Pool struct {
+	opts  PoolOptions
+	queue chan poolTask
+
+	// ctx is cancelled when Shutdown gives up waiting, to stop tasks still running
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.RWMutex
+	closed     bool
+	quit       chan struct{}
+	submitters sync.WaitGroup
+	workers    sync.WaitGroup
+}
+
+type poolTask struct {
+	enqueuedAt time.Time
+	run        func(wait time.Duration)
+}
+
+// NewPool starts the workers of a new pool
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		opts:   opts,
+		queue:  make(chan poolTask, opts.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		quit:   make(chan struct{}),
+	}
+
+	p.workers.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+	for task := range p.queue {
+		p.reportQueueDepth()
+		task.run(time.Since(task.enqueuedAt))
+	}
+}
+
+func (p *Pool) reportQueueDepth() {
+	if p.opts.OnQueueDepth != nil {
+		p.opts.OnQueueDepth(len(p.queue))
+	}
+}
+
+// QueueDepth returns the number of tasks waiting for a worker
+func (p *Pool) QueueDepth() int {
+	return len(p.queue)
+}
+
+// Submit queues f to run on p and returns its future.
+// When the queue is full Submit blocks until there is room or ctx is done,
+// or returns ErrPoolQueueFull if the pool was created with RejectWhenFull.
+func Submit[T any](ctx context.Context, p *Pool, f func(ctx context.Context) (T, error)) (TypedFuture[T], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return nil, ErrPoolClosed
+	}
+	p.submitters.Add(1)
+	p.mu.RUnlock()
+	defer p.submitters.Done()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(p.ctx, cancel)
+	fut := &future[T]{
+		done: make(chan struct{}),
+		cancel: func() {
+			stop()
+			cancel()
+		},
+	}
+
+	task := poolTask{
+		enqueuedAt: time.Now(),
+		run: func(wait time.Duration) {
+			start := time.Now()
+			fut.run(runCtx, f)
+			if p.opts.OnTaskDone != nil {
+				p.opts.OnTaskDone(wait, time.Since(start), fut.err)
+			}
+		},
+	}
+
+	if p.opts.RejectWhenFull {
+		select {
+		case p.queue <- task:
+		default:
+			fut.cancel()
+			return nil, ErrPoolQueueFull
+		}
+	} else {
+		select {
+		case p.queue <- task:
+		case <-p.quit:
+			fut.cancel()
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			fut.cancel()
+			return nil, ctx.Err()
+		}
+	}
+
+	p.reportQueueDepth()
+	return fut, nil
+}
+
+// Shutdown stops accepting tasks and waits for queued and running tasks to finish.
+// If ctx is done first, the contexts of remaining tasks are cancelled and ctx.Err() is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	close(p.quit)
+	p.mu.Unlock()
+
+	// No submitter can send after this, so the queue can be closed safely
+	p.submitters.Wait()
+	close(p.queue)
+
+	drained := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
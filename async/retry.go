@@ -0,0 +1,88 @@
+package async
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures Retry
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls, including the first one (values below 1 mean a single call)
+	MaxAttempts int
+	// InitialInterval is the backoff cap before the second attempt
+	InitialInterval time.Duration
+	// MaxInterval caps the backoff between attempts
+	MaxInterval time.Duration
+	// Multiplier grows the backoff cap after each attempt (default 2)
+	Multiplier float64
+	// MaxElapsedTime stops retrying once exceeded (0 means no limit)
+	MaxElapsedTime time.Duration
+	// Retryable decides whether an error should be retried (nil retries every error)
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries up to 3 times in total with 100ms..2s full-jitter backoff
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     3,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     2 * time.Second,
+		Multiplier:      2,
+		MaxElapsedTime:  10 * time.Second,
+	}
+}
+
+// Backoff returns a random delay in [0, ceiling) where ceiling grows exponentially with attempt (full jitter)
+func (policy RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	ceiling := float64(policy.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if policy.MaxInterval > 0 && ceiling > float64(policy.MaxInterval) {
+		ceiling = float64(policy.MaxInterval)
+	}
+	if ceiling < 1 {
+		return 0
+	} else if ceiling >= math.MaxInt64 {
+		return time.Duration(rand.Int64N(math.MaxInt64))
+	}
+
+	return time.Duration(rand.Int64N(int64(ceiling)))
+}
+
+// Retry calls fn until it succeeds, returns a non-retryable error, or the policy is exhausted.
+// The last error is returned when giving up; ctx.Err() is returned if ctx is done while waiting.
+func Retry[T any](ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+
+		if attempt >= policy.MaxAttempts || (policy.Retryable != nil && !policy.Retryable(err)) {
+			return result, err
+		}
+
+		delay := policy.Backoff(attempt)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return result, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -12,3 +12,7 @@ func InitAws() {
 func InitRedis(cacheClusterName string) (*awsRedis.RedisClientWrapper, error) {
 	return awsRedis.InitRedis(cacheClusterName)
 }
+
+func ConnectRedis(opts awsRedis.ConnectOptions) (*awsRedis.RedisClientWrapper, error) {
+	return awsRedis.Connect(opts)
+}
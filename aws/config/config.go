@@ -3,6 +3,9 @@ package awsConfig
 import (
 	"fmt"
 
+	"github.com/BeeTechHub/go-common/async"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
@@ -26,3 +29,21 @@ func GetAWSSession() *session.Session {
 
 	return awsSession
 }
+
+// IsRetryableError reports whether an aws sdk error is transient (throttling, 5xx, connection errors)
+func IsRetryableError(err error) bool {
+	return request.IsErrorRetryable(err) || request.IsErrorThrottle(err)
+}
+
+// DefaultRetryPolicy is the retry policy used by the aws wrappers
+func DefaultRetryPolicy() async.RetryPolicy {
+	policy := async.DefaultRetryPolicy()
+	policy.Retryable = IsRetryableError
+	return policy
+}
+
+// SingleAttempt disables the sdk retryer of a request that is already retried with a RetryPolicy,
+// so that the attempts do not multiply
+func SingleAttempt(r *request.Request) {
+	r.Retryer = client.NoOpRetryer{}
+}
@@ -0,0 +1,68 @@
+package awsRedis
+
+import (
+	"context"
+
+	"github.com/BeeTechHub/go-common/async"
+	"github.com/redis/go-redis/v9"
+)
+
+// AttachCircuitBreaker routes every command and pipeline of the underlying client through breaker.
+// While the breaker is open, commands fail fast with *async.CircuitOpenError. redis.Nil is not counted as a failure.
+func (redisClient RedisClientWrapper) AttachCircuitBreaker(breaker *async.CircuitBreaker) error {
+	if redisClient.universal() == nil {
+		return nilClientError
+	}
+
+	redisClient.universal().AddHook(breakerHook{breaker})
+	return nil
+}
+
+type breakerHook struct {
+	breaker *async.CircuitBreaker
+}
+
+func (hook breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (hook breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		var cmdErr error
+		_, err := async.Guard(hook.breaker, func() (struct{}, error) {
+			cmdErr = next(ctx, cmd)
+			return struct{}{}, ignoreNil(cmdErr)
+		})
+		if err != nil && cmdErr == nil {
+			// Rejected by the breaker, next was not called
+			return err
+		}
+
+		return cmdErr
+	}
+}
+
+func (hook breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		var cmdErr error
+		_, err := async.Guard(hook.breaker, func() (struct{}, error) {
+			cmdErr = next(ctx, cmds)
+			return struct{}{}, ignoreNil(cmdErr)
+		})
+		if err != nil && cmdErr == nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+
+		return cmdErr
+	}
+}
+
+func ignoreNil(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package awsRedis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes the values stored by SetJSON and GetOrLoad
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	MsgpackCodec  Codec = msgpackCodec{}
+	GzipJSONCodec Codec = Gzip(JSONCodec)
+)
+
+// DefaultCodec is used when no codec is given
+var DefaultCodec = JSONCodec
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type gzipCodec struct {
+	codec Codec
+}
+
+// Gzip compresses the output of codec, for large values
+func Gzip(codec Codec) Codec {
+	return gzipCodec{codec}
+}
+
+func (c gzipCodec) Marshal(v any) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Unmarshal(data []byte, v any) error {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(data, v)
+}
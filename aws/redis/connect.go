@@ -0,0 +1,276 @@
+package awsRedis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	config "github.com/BeeTechHub/go-common/aws/config"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elasticache"
+	"github.com/redis/go-redis/v9"
+)
+
+// ConnectOptions configures Connect. Zero values keep the go-redis defaults.
+//
+// The servers are Addrs when set, otherwise they are discovered on ElastiCache from ReplicationGroupId or CacheClusterId.
+type ConnectOptions struct {
+	// Addrs are the servers, the cluster seed nodes or, with MasterName, the sentinels
+	Addrs []string
+	// ClusterMode connects to Addrs as a Redis Cluster
+	ClusterMode bool
+	// MasterName is the name of the master monitored by the sentinels in Addrs
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+
+	ReplicationGroupId string
+	// CacheClusterId is a cache cluster, used with its replication group when it belongs to one
+	CacheClusterId string
+
+	// ReadFromReplicas routes read-only commands to the replicas, and to the primaries
+	ReadFromReplicas bool
+
+	Username string
+	// Password is the AUTH token
+	Password string
+	// DB is only supported by single node and sentinel clients
+	DB int
+
+	// TLS is enabled when set, or when ElastiCache reports transit encryption
+	TLS bool
+	// TLSCAFile is a PEM file of the CA certificates used to verify the server
+	TLSCAFile   string
+	TLSInsecure bool
+
+	PoolSize     int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// topology is the layout of the servers found by discovery
+type topology struct {
+	// addrs are the seed nodes of a cluster, or the primary followed by its replicas
+	addrs   []string
+	cluster bool
+	tls     bool
+}
+
+func endpointAddr(endpoint *elasticache.Endpoint) (string, bool) {
+	if endpoint == nil || endpoint.Address == nil || endpoint.Port == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s:%d", *endpoint.Address, *endpoint.Port), true
+}
+
+func describeReplicationGroup(svc *elasticache.ElastiCache, replicationGroupId string) (topology, error) {
+	result, err := svc.DescribeReplicationGroups(&elasticache.DescribeReplicationGroupsInput{
+		ReplicationGroupId: aws.String(replicationGroupId),
+	})
+	if err != nil {
+		return topology{}, fmt.Errorf("Describe redis replication group %s failed: %w", replicationGroupId, err)
+	}
+	if len(result.ReplicationGroups) <= 0 || result.ReplicationGroups[0] == nil {
+		return topology{}, fmt.Errorf("Missing elasticache replication group with name: %s", replicationGroupId)
+	}
+
+	group := result.ReplicationGroups[0]
+	found := topology{tls: aws.BoolValue(group.TransitEncryptionEnabled)}
+
+	if aws.BoolValue(group.ClusterEnabled) {
+		addr, ok := endpointAddr(group.ConfigurationEndpoint)
+		if !ok {
+			return topology{}, fmt.Errorf("Missing elasticache configuration endpoint of replication group: %s", replicationGroupId)
+		}
+		found.addrs, found.cluster = []string{addr}, true
+		return found, nil
+	}
+
+	if len(group.NodeGroups) <= 0 || group.NodeGroups[0] == nil {
+		return topology{}, fmt.Errorf("Missing elasticache node group of replication group: %s", replicationGroupId)
+	}
+	nodeGroup := group.NodeGroups[0]
+
+	primary, ok := endpointAddr(nodeGroup.PrimaryEndpoint)
+	if !ok {
+		return topology{}, fmt.Errorf("Missing elasticache primary endpoint of replication group: %s", replicationGroupId)
+	}
+	found.addrs = []string{primary}
+	for _, member := range nodeGroup.NodeGroupMembers {
+		if member == nil || aws.StringValue(member.CurrentRole) != "replica" {
+			continue
+		}
+		if addr, ok := endpointAddr(member.ReadEndpoint); ok {
+			found.addrs = append(found.addrs, addr)
+		}
+	}
+	return found, nil
+}
+
+func describeCacheCluster(svc *elasticache.ElastiCache, cacheClusterId string) (topology, error) {
+	result, err := svc.DescribeCacheClusters(&elasticache.DescribeCacheClustersInput{
+		CacheClusterId:    aws.String(cacheClusterId),
+		ShowCacheNodeInfo: aws.Bool(true),
+	})
+	if err != nil {
+		return topology{}, fmt.Errorf("Describe redis cluster %s failed: %w", cacheClusterId, err)
+	}
+	if len(result.CacheClusters) <= 0 || result.CacheClusters[0] == nil {
+		return topology{}, fmt.Errorf("Missing elasticache cluster with name: %s", cacheClusterId)
+	}
+
+	cluster := result.CacheClusters[0]
+	if cluster.ReplicationGroupId != nil {
+		return describeReplicationGroup(svc, *cluster.ReplicationGroupId)
+	}
+
+	if len(cluster.CacheNodes) <= 0 || cluster.CacheNodes[0] == nil {
+		return topology{}, fmt.Errorf("Missing elasticache cluster node with name: %s", cacheClusterId)
+	}
+	addr, ok := endpointAddr(cluster.CacheNodes[0].Endpoint)
+	if !ok {
+		return topology{}, fmt.Errorf("Missing elasticache cluster address with name: %s", cacheClusterId)
+	}
+	return topology{addrs: []string{addr}, tls: aws.BoolValue(cluster.TransitEncryptionEnabled)}, nil
+}
+
+// discover returns the servers of opts, describing them on ElastiCache when Addrs is empty
+func (opts ConnectOptions) discover() (topology, error) {
+	if len(opts.Addrs) > 0 {
+		return topology{addrs: opts.Addrs, cluster: opts.ClusterMode, tls: opts.TLS}, nil
+	}
+
+	svc := elasticache.New(config.GetAWSSession())
+	var found topology
+	var err error
+	switch {
+	case opts.ReplicationGroupId != "":
+		found, err = describeReplicationGroup(svc, opts.ReplicationGroupId)
+	case opts.CacheClusterId != "":
+		found, err = describeCacheCluster(svc, opts.CacheClusterId)
+	default:
+		return topology{}, errors.New("redis: Addrs, ReplicationGroupId or CacheClusterId is required")
+	}
+	if err != nil {
+		return topology{}, err
+	}
+
+	found.tls = found.tls || opts.TLS
+	return found, nil
+}
+
+func (opts ConnectOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: opts.TLSInsecure}
+
+	if opts.TLSCAFile != "" {
+		pem, err := os.ReadFile(opts.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificate found in %s", opts.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// newClient builds the client matching the topology:
+//   - a sentinel client when MasterName is set
+//   - a cluster client for a cluster
+//   - a cluster client with one slot range over the primary and its replicas, to read from the replicas
+//   - a single node client otherwise
+func (opts ConnectOptions) newClient(found topology) (redis.UniversalClient, error) {
+	var tlsConfig *tls.Config
+	if found.tls {
+		var err error
+		if tlsConfig, err = opts.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.MasterName != "" {
+		return redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs:            found.addrs,
+			MasterName:       opts.MasterName,
+			SentinelUsername: opts.SentinelUsername,
+			SentinelPassword: opts.SentinelPassword,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.DB,
+			ReadOnly:         opts.ReadFromReplicas,
+			RouteRandomly:    opts.ReadFromReplicas,
+			TLSConfig:        tlsConfig,
+			PoolSize:         opts.PoolSize,
+			DialTimeout:      opts.DialTimeout,
+			ReadTimeout:      opts.ReadTimeout,
+			WriteTimeout:     opts.WriteTimeout,
+		}), nil
+	}
+
+	if found.cluster || (opts.ReadFromReplicas && len(found.addrs) > 1) {
+		clusterOptions := &redis.ClusterOptions{
+			Addrs:         found.addrs,
+			Username:      opts.Username,
+			Password:      opts.Password,
+			ReadOnly:      opts.ReadFromReplicas,
+			RouteRandomly: opts.ReadFromReplicas,
+			TLSConfig:     tlsConfig,
+			PoolSize:      opts.PoolSize,
+			DialTimeout:   opts.DialTimeout,
+			ReadTimeout:   opts.ReadTimeout,
+			WriteTimeout:  opts.WriteTimeout,
+		}
+		if !found.cluster {
+			nodes := make([]redis.ClusterNode, len(found.addrs))
+			for i, addr := range found.addrs {
+				nodes[i] = redis.ClusterNode{Addr: addr}
+			}
+			clusterOptions.ClusterSlots = func(ctx context.Context) ([]redis.ClusterSlot, error) {
+				return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: nodes}}, nil
+			}
+		}
+		return redis.NewClusterClient(clusterOptions), nil
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:         found.addrs[0],
+		Username:     opts.Username,
+		Password:     opts.Password,
+		DB:           opts.DB,
+		TLSConfig:    tlsConfig,
+		PoolSize:     opts.PoolSize,
+		DialTimeout:  opts.DialTimeout,
+		ReadTimeout:  opts.ReadTimeout,
+		WriteTimeout: opts.WriteTimeout,
+	}), nil
+}
+
+// Connect discovers the servers of opts, builds the matching client and pings it with PingRetryPolicy
+func Connect(opts ConnectOptions) (*RedisClientWrapper, error) {
+	found, err := opts.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	redisClient, err := opts.newClient(found)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ping(redisClient); err != nil {
+		_ = redisClient.Close()
+		return nil, fmt.Errorf("Failed to ping Redis: %w", err)
+	}
+
+	return newRedisClientWrapper(redisClient), nil
+}
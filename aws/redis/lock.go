@@ -0,0 +1,317 @@
+package awsRedis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/BeeTechHub/go-common/async"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotObtained is returned when the lock is held by someone else
+var ErrNotObtained = errors.New("redis: lock not obtained")
+
+// ErrNotHeld is returned when the lock expired or was taken over
+var ErrNotHeld = errors.New("redis: lock not held")
+
+// The scripts only touch the key while it still holds the token of the caller
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// LockOptions configures a Locker. Zero values use the defaults.
+type LockOptions struct {
+	// TTL is the lease of the lock (default 30s)
+	TTL time.Duration
+	// RenewInterval is how often a held lock extends its lease (default TTL/3, negative disables renewal)
+	RenewInterval time.Duration
+	// RetryPolicy paces the attempts of Lock (default every 50ms..1s until ctx is done). Its Retryable is ignored:
+	// Lock retries while the lock is held or redis fails transiently.
+	RetryPolicy *async.RetryPolicy
+	// OnLost is called when a renewal finds the lock lost
+	OnLost func(key string, err error)
+}
+
+// Locker takes locks on one client, or on several independent clients with the Redlock algorithm:
+// a lock is held when a majority of the clients granted it within its TTL.
+type Locker struct {
+	clients []RedisClientWrapper
+	opts    LockOptions
+}
+
+// NewLocker returns a Locker over clients, which should be independent masters when there are several
+func NewLocker(clients []RedisClientWrapper, opts ...LockOptions) *Locker {
+	var opt LockOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.TTL <= 0 {
+		opt.TTL = 30 * time.Second
+	}
+	if opt.RenewInterval == 0 {
+		opt.RenewInterval = opt.TTL / 3
+	}
+	if opt.RetryPolicy == nil {
+		opt.RetryPolicy = &async.RetryPolicy{
+			MaxAttempts:     math.MaxInt32,
+			InitialInterval: 50 * time.Millisecond,
+			MaxInterval:     time.Second,
+		}
+	}
+
+	return &Locker{clients: clients, opts: opt}
+}
+
+// Locker returns a Locker on this client
+func (redisClient RedisClientWrapper) Locker(opts ...LockOptions) *Locker {
+	return NewLocker([]RedisClientWrapper{redisClient}, opts...)
+}
+
+func (locker *Locker) quorum() int {
+	return len(locker.clients)/2 + 1
+}
+
+// drift is the clock drift allowance of Redlock
+func (locker *Locker) drift() time.Duration {
+	return locker.opts.TTL/100 + 2*time.Millisecond
+}
+
+// each calls fn on every client concurrently and returns how many returned true, with the joined errors
+func (locker *Locker) each(ctx context.Context, fn func(ctx context.Context, client RedisClientWrapper) (bool, error)) (int, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	count := 0
+	errs := []error{}
+	for _, client := range locker.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := fn(ctx, client)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				count++
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return count, errors.Join(errs...)
+}
+
+func runScript(ctx context.Context, client RedisClientWrapper, script *redis.Script, key string, args ...any) (bool, error) {
+	if client.universal() == nil {
+		return false, nilClientError
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := script.Run(ctx, client.universal(), []string{key}, args...).Int64()
+	return result == 1, err
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// TryLock takes the lock of key once, returning ErrNotObtained when it is held
+func (locker *Locker) TryLock(ctx context.Context, key string) (*Lock, error) {
+	if len(locker.clients) == 0 {
+		return nil, nilClientError
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	count, err := locker.each(ctx, func(ctx context.Context, client RedisClientWrapper) (bool, error) {
+		return client.SetNXDataToCacheWithContext(ctx, key, token, locker.opts.TTL)
+	})
+	validUntil := start.Add(locker.opts.TTL - locker.drift())
+
+	if count < locker.quorum() || time.Now().After(validUntil) {
+		// Release the minority that granted it so that it does not block others until it expires
+		_, _ = locker.each(context.WithoutCancel(ctx), func(ctx context.Context, client RedisClientWrapper) (bool, error) {
+			return runScript(ctx, client, releaseScript, key, token)
+		})
+		if err != nil {
+			return nil, errors.Join(ErrNotObtained, err)
+		}
+		return nil, ErrNotObtained
+	}
+
+	lock := &Lock{
+		locker:     locker,
+		key:        key,
+		token:      token,
+		validUntil: validUntil,
+		lost:       make(chan struct{}),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if locker.opts.RenewInterval > 0 {
+		go lock.renew()
+	} else {
+		close(lock.done)
+	}
+	return lock, nil
+}
+
+// retryableLockError reports whether every error joined in err is ErrNotObtained or transient. Other errors,
+// e.g. a nil client, a script or an auth error, fail again on every attempt.
+func retryableLockError(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if !retryableLockError(err) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrNotObtained) || errors.Is(err, context.DeadlineExceeded) || IsTransientError(err)
+}
+
+// Lock takes the lock of key, retrying with opts.RetryPolicy while it is held or redis fails transiently
+func (locker *Locker) Lock(ctx context.Context, key string) (*Lock, error) {
+	policy := *locker.opts.RetryPolicy
+	policy.Retryable = retryableLockError
+
+	return async.Retry(ctx, policy, func(ctx context.Context) (*Lock, error) {
+		return locker.TryLock(ctx, key)
+	})
+}
+
+// Lock is a held lock. Its lease is renewed in the background until Unlock, or until Lost is closed.
+type Lock struct {
+	locker *Locker
+	key    string
+	token  string
+
+	mu         sync.Mutex
+	validUntil time.Time
+
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (lock *Lock) Key() string {
+	return lock.key
+}
+
+func (lock *Lock) Token() string {
+	return lock.token
+}
+
+// Lost is closed when a renewal finds the lock expired or taken over
+func (lock *Lock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// ValidUntil is the time the lease runs out unless it is extended
+func (lock *Lock) ValidUntil() time.Time {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+
+	return lock.validUntil
+}
+
+// Extend resets the lease to the TTL, returning ErrNotHeld when the lock expired or was taken over
+func (lock *Lock) Extend(ctx context.Context) error {
+	ttl := lock.locker.opts.TTL
+	start := time.Now()
+	count, err := lock.locker.each(ctx, func(ctx context.Context, client RedisClientWrapper) (bool, error) {
+		return runScript(ctx, client, extendScript, lock.key, lock.token, ttl.Milliseconds())
+	})
+	if count < lock.locker.quorum() {
+		if err != nil {
+			return err
+		}
+		return ErrNotHeld
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	lock.validUntil = start.Add(ttl - lock.locker.drift())
+	return nil
+}
+
+func (lock *Lock) renew() {
+	defer close(lock.done)
+
+	ticker := time.NewTicker(lock.locker.opts.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := lock.Extend(context.Background())
+		if err == nil {
+			continue
+		}
+		// Redis errors are retried on the next tick while the lease lasts
+		if errors.Is(err, ErrNotHeld) || time.Now().After(lock.ValidUntil()) {
+			lock.markLost(err)
+			return
+		}
+	}
+}
+
+func (lock *Lock) markLost(err error) {
+	lock.lostOnce.Do(func() {
+		close(lock.lost)
+		if lock.locker.opts.OnLost != nil {
+			lock.locker.opts.OnLost(lock.key, err)
+		}
+	})
+}
+
+// Unlock stops the renewal and releases the lock, returning ErrNotHeld when it was no longer held
+func (lock *Lock) Unlock(ctx context.Context) error {
+	lock.stopOnce.Do(func() {
+		close(lock.stop)
+	})
+	<-lock.done
+
+	count, err := lock.locker.each(ctx, func(ctx context.Context, client RedisClientWrapper) (bool, error) {
+		return runScript(ctx, client, releaseScript, lock.key, lock.token)
+	})
+	if count < lock.locker.quorum() {
+		if err != nil {
+			return err
+		}
+		return ErrNotHeld
+	}
+	return nil
+}
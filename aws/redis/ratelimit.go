@@ -0,0 +1,202 @@
+package awsRedis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	// SlidingWindowLog keeps a sorted set of the request times of the last Window
+	SlidingWindowLog RateLimitAlgorithm = iota
+	// FixedWindow counts the requests of windows starting at the first request
+	FixedWindow
+	// TokenBucket refills Limit tokens per Window, allowing bursts of up to Limit requests
+	TokenBucket
+)
+
+// The scripts read the time of redis so that the pods do not depend on their clocks.
+// They return {allowed, remaining, retry after ms, reset after ms}.
+var (
+	slidingWindowScript = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+local retry = 0
+if count + n <= limit then
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+	end
+	count = count + n
+	allowed = 1
+else
+	local rank = count + n - limit - 1
+	local entry = redis.call("ZRANGE", KEYS[1], rank, rank, "WITHSCORES")
+	retry = tonumber(entry[2]) + window - now
+end
+
+local reset = 0
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+	redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+end
+return {allowed, limit - count, math.ceil(retry / 1000), math.ceil(reset / 1000)}`)
+
+	fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ttl = redis.call("PTTL", KEYS[1])
+if count + n > limit then
+	if ttl < 0 then
+		ttl = window
+	end
+	return {0, limit - count, ttl, ttl}
+end
+
+count = redis.call("INCRBY", KEYS[1], n)
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], window)
+	ttl = window
+end
+return {1, limit - count, 0, ttl}`)
+
+	tokenBucketScript = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local capacity = tonumber(ARGV[1])
+local rate = capacity / tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	retry = (n - tokens) / rate
+end
+
+local reset = (capacity - tokens) / rate
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", string.format("%.0f", now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(reset / 1000) + 1000)
+return {allowed, math.floor(tokens), math.ceil(retry / 1000), math.ceil(reset / 1000)}`)
+)
+
+// RateLimit is the quota of a RateLimiter: Limit requests per Window
+type RateLimit struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int64
+	Window    time.Duration
+	// KeyPrefix is prepended to the limited keys (default "ratelimit:")
+	KeyPrefix string
+}
+
+// RateLimitResult is the outcome of a request against a RateLimiter
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// RetryAfter is how long to wait before the request can be allowed, 0 when it was allowed
+	RetryAfter time.Duration
+	// ResetAfter is the time until the window restarts (FixedWindow), the oldest request leaves it (SlidingWindowLog)
+	// or the bucket is full again (TokenBucket)
+	ResetAfter time.Duration
+}
+
+// RateLimiter limits requests per key across every process sharing the redis server
+type RateLimiter struct {
+	redisClient RedisClientWrapper
+	limit       RateLimit
+}
+
+// RateLimiter returns a RateLimiter enforcing limit on this client
+func (redisClient RedisClientWrapper) RateLimiter(limit RateLimit) *RateLimiter {
+	if limit.KeyPrefix == "" {
+		limit.KeyPrefix = "ratelimit:"
+	}
+	return &RateLimiter{redisClient: redisClient, limit: limit}
+}
+
+func (limiter *RateLimiter) Limit() RateLimit {
+	return limiter.limit
+}
+
+// Allow counts one request of key
+func (limiter *RateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return limiter.AllowN(ctx, key, 1)
+}
+
+// AllowN counts n requests of key, which are all allowed or all rejected
+func (limiter *RateLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	limit := limiter.limit
+	if limiter.redisClient.universal() == nil {
+		return RateLimitResult{}, nilClientError
+	}
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return RateLimitResult{}, errors.New("redis: rate limit needs a positive Limit and Window")
+	}
+	if n <= 0 || n > limit.Limit {
+		return RateLimitResult{}, fmt.Errorf("redis: cannot take %d requests from a limit of %d", n, limit.Limit)
+	}
+
+	var script *redis.Script
+	args := []any{limit.Limit, 0, n}
+	switch limit.Algorithm {
+	case SlidingWindowLog:
+		token, err := newToken()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		script, args[1] = slidingWindowScript, limit.Window.Microseconds()
+		args = append(args, token)
+	case FixedWindow:
+		script, args[1] = fixedWindowScript, limit.Window.Milliseconds()
+	case TokenBucket:
+		script, args[1] = tokenBucketScript, limit.Window.Microseconds()
+	default:
+		return RateLimitResult{}, fmt.Errorf("redis: unknown rate limit algorithm %d", limit.Algorithm)
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	values, err := script.Run(ctx, limiter.redisClient.universal(), []string{limit.KeyPrefix + key}, args...).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 4 {
+		return RateLimitResult{}, fmt.Errorf("redis: unexpected rate limit script result %v", values)
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Limit,
+		Remaining:  max(values[1], 0),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
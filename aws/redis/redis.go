@@ -4,15 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	config "github.com/BeeTechHub/go-common/aws/config"
+	"github.com/BeeTechHub/go-common/async"
 	"github.com/BeeTechHub/go-common/configs"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/elasticache"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -20,111 +22,142 @@ import (
 
 var nilClientError = errors.New("Access redis failed because redis client nil")
 
+// PingRetryPolicy is used when pinging a new redis client during init, it only retries transient errors
+var PingRetryPolicy = func() async.RetryPolicy {
+	policy := async.DefaultRetryPolicy()
+	policy.Retryable = IsTransientError
+	return policy
+}()
+
+// DefaultTimeout bounds each call whose context has no deadline, 0 disables it
+var DefaultTimeout = 5 * time.Second
+
 type RedisClientWrapper struct {
+	// Client is the single node or sentinel client, nil when connected through a *redis.ClusterClient
 	Client *redis.Client
+	// Universal is the client every command goes through: Client or a *redis.ClusterClient.
+	// Wrappers built with only Client use it.
+	Universal redis.UniversalClient
+}
+
+func newRedisClientWrapper(client redis.UniversalClient) *RedisClientWrapper {
+	single, _ := client.(*redis.Client)
+	return &RedisClientWrapper{Client: single, Universal: client}
+}
+
+// universal returns the client commands go through, nil when the wrapper has none
+func (redisClient RedisClientWrapper) universal() redis.UniversalClient {
+	if redisClient.Universal != nil {
+		return redisClient.Universal
+	}
+	if redisClient.Client != nil {
+		return redisClient.Client
+	}
+	return nil
+}
+
+// isCluster reports whether keys of a command may live on different nodes
+func (redisClient RedisClientWrapper) isCluster() bool {
+	_, ok := redisClient.universal().(*redis.ClusterClient)
+	return ok
 }
 
-func initClientLocal() (*RedisClientWrapper, error) {
+// localOptions returns the comma separated CACHE_ADDRS, or localhost:6379, as a cluster when CACHE_CLUSTER_MODE is true
+func localOptions() ConnectOptions {
+	opts := ConnectOptions{Addrs: []string{"localhost:6379"}} // Default local Redis address
+	if addrs := os.Getenv("CACHE_ADDRS"); addrs != "" {
+		opts.Addrs = strings.Split(addrs, ",")
+	}
+	opts.ClusterMode, _ = strconv.ParseBool(os.Getenv("CACHE_CLUSTER_MODE"))
+	return opts
+}
+
+func initClientLocal(opts ConnectOptions) (*RedisClientWrapper, error) {
 	fmt.Println("Initializing Redis client for local connection...")
 
 	// Connect to the local Redis server
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379", // Default local Redis address
-		Password: "",               // No password for local Redis by default
-		DB:       0,                // Default DB
-	})
-
-	// Test the connection
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		fmt.Println("Failed to connect to local Redis: %v\n", err)
+	redisClient, err := Connect(opts)
+	if err != nil {
+		fmt.Printf("Failed to connect to local Redis: %v\n", err)
 		return nil, err
-	} else {
-		fmt.Println("Connected to local Redis successfully!")
-		return &RedisClientWrapper{redisClient}, nil
 	}
+
+	fmt.Println("Connected to local Redis successfully!")
+	return redisClient, nil
 }
 
 func initClientAws(cacheClusterName string) (*RedisClientWrapper, error) {
 	fmt.Println("Redis client start...")
-	// Set up AWS session and Elasticache client
-	sess := config.GetAWSSession()
-	svc := elasticache.New(sess)
-
-	clusterName := cacheClusterName
-	// Get the Elasticache Redis cluster's endpoint address and port
-	result, err := svc.DescribeCacheClusters(&elasticache.DescribeCacheClustersInput{
-		CacheClusterId:    aws.String(clusterName),
-		ShowCacheNodeInfo: aws.Bool(true),
-	})
+	redisClient, err := Connect(ConnectOptions{CacheClusterId: cacheClusterName})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case elasticache.ErrCodeCacheClusterNotFoundFault:
-				fmt.Println(elasticache.ErrCodeCacheClusterNotFoundFault, aerr.Error())
-			case elasticache.ErrCodeInvalidParameterValueException:
-				fmt.Println(elasticache.ErrCodeInvalidParameterValueException, aerr.Error())
-			case elasticache.ErrCodeInvalidParameterCombinationException:
-				fmt.Println(elasticache.ErrCodeInvalidParameterCombinationException, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and Message from an error.
-			fmt.Printf("Get redis cluster error:%s", err.Error())
-		}
-
+		fmt.Printf("Get redis cluster error:%s\n", err.Error())
 		return nil, err
 	}
 
-	// print the endpoint of the cluster
-	if len(result.CacheClusters) <= 0 || result.CacheClusters[0] == nil ||
-		len(result.CacheClusters[0].CacheNodes) <= 0 || result.CacheClusters[0].CacheNodes[0] == nil {
-		errMessage := fmt.Sprintf("Missing elasticache cluster with name: %s", clusterName)
-		fmt.Println(errMessage)
-		return nil, errors.New(errMessage)
-	}
+	return redisClient, nil
+}
 
-	endpoint := result.CacheClusters[0].CacheNodes[0].Endpoint.Address
-	if endpoint == nil {
-		errMessage := fmt.Sprintf("Missing elasticache cluster address with name: %s", clusterName)
-		fmt.Println(errMessage)
-		return nil, errors.New(errMessage)
+// IsTransientError reports whether err is a network error or a redis error worth retrying (loading, failover)
+func IsTransientError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
 	}
 
-	port := result.CacheClusters[0].CacheNodes[0].Endpoint.Port
-	if port == nil {
-		errMessage := fmt.Sprintf("Missing elasticache cluster port with name: %s", clusterName)
-		fmt.Println(errMessage)
-		return nil, errors.New(errMessage)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
 	}
 
-	// Create a Redis client and connect to the cluster
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("%s:%d", *endpoint, *port),
-	})
-
-	_, err = redisClient.Ping(context.Background()).Result()
-	if err != nil {
-		return nil, fmt.Errorf("Failed to ping Redis: %w", err)
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range []string{"LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY"} {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	return &RedisClientWrapper{redisClient}, nil
+func ping(redisClient redis.UniversalClient) error {
+	_, err := async.Retry(context.Background(), PingRetryPolicy, func(ctx context.Context) (string, error) {
+		return redisClient.Ping(ctx).Result()
+	})
+	return err
 }
 
+// InitRedis connects to the servers in CACHE_ADDRS when CACHE_HOST is local, as a cluster when CACHE_CLUSTER_MODE
+// is true, otherwise discovers cacheClusterName
+// on ElastiCache. Use Connect for replication groups, sentinels, replica reads, TLS or AUTH.
 func InitRedis(cacheClusterName string) (*RedisClientWrapper, error) {
 	if configs.GetCacheHost() == "local" {
-		return initClientLocal()
+		return initClientLocal(localOptions())
 	} else {
 		return initClientAws(cacheClusterName)
 	}
 }
 
-func (redisClient RedisClientWrapper) SetDataToCache(key string, value string, exprire time.Duration) error {
-	if redisClient.Client == nil {
+// withTimeout applies DefaultTimeout to ctx unless it already has a deadline
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok || DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
+
+func (redisClient RedisClientWrapper) SetDataToCacheWithContext(ctx context.Context, key string, value string, exprire time.Duration) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.Set(context.Background(), key, value, exprire).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().Set(ctx, key, value, exprire).Result()
 	if err != nil {
 		return err
 	}
@@ -132,12 +165,19 @@ func (redisClient RedisClientWrapper) SetDataToCache(key string, value string, e
 	return nil
 }
 
-func (redisClient RedisClientWrapper) GetValueFromKey(key string) (*string, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) SetDataToCache(key string, value string, exprire time.Duration) error {
+	return redisClient.SetDataToCacheWithContext(context.Background(), key, value, exprire)
+}
+
+func (redisClient RedisClientWrapper) GetValueFromKeyWithContext(ctx context.Context, key string) (*string, error) {
+	if redisClient.universal() == nil {
 		return nil, nilClientError
 	}
 
-	data, err := redisClient.Client.Get(context.Background(), key).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	data, err := redisClient.universal().Get(ctx, key).Result()
 	if err == redis.Nil {
 		return nil, nil
 	} else if err != nil {
@@ -147,20 +187,73 @@ func (redisClient RedisClientWrapper) GetValueFromKey(key string) (*string, erro
 	return &data, nil
 }
 
-func (redisClient RedisClientWrapper) DeleteDataFromKeys(keys []string) error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) GetValueFromKey(key string) (*string, error) {
+	return redisClient.GetValueFromKeyWithContext(context.Background(), key)
+}
+
+// GetValuesFromKeysWithContext returns the values of keys in order, nil for the missing ones
+func (redisClient RedisClientWrapper) GetValuesFromKeysWithContext(ctx context.Context, keys []string) ([]*string, error) {
+	if redisClient.universal() == nil {
+		return nil, nilClientError
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result := make([]*string, len(keys))
+	if redisClient.isCluster() {
+		// MGET fails with CROSSSLOT on a cluster, the pipeline sends each GET to the node of its key
+		pipe := redisClient.universal().Pipeline()
+		cmds := make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		for i, cmd := range cmds {
+			value, err := cmd.Result()
+			if errors.Is(err, redis.Nil) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			result[i] = &value
+		}
+		return result, nil
+	}
+
+	values, err := redisClient.universal().MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			result[i] = &s
+		}
+	}
+	return result, nil
+}
+
+func (redisClient RedisClientWrapper) DeleteDataFromKeysWithContext(ctx context.Context, keys []string) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	pipe := redisClient.Client.Pipeline()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	pipe := redisClient.universal().Pipeline()
 	for _, key := range keys {
 		if len(key) <= 0 {
 			continue
 		}
-		pipe.Del(context.Background(), key)
+		pipe.Del(ctx, key)
 	}
 
-	_, _err := pipe.Exec(context.Background())
+	_, _err := pipe.Exec(ctx)
 	if _err != nil {
 		return _err
 	}
@@ -168,21 +261,35 @@ func (redisClient RedisClientWrapper) DeleteDataFromKeys(keys []string) error {
 	return nil
 }
 
-func (redisClient RedisClientWrapper) FlushAllAsync() error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) DeleteDataFromKeys(keys []string) error {
+	return redisClient.DeleteDataFromKeysWithContext(context.Background(), keys)
+}
+
+func (redisClient RedisClientWrapper) FlushAllAsyncWithContext(ctx context.Context) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.FlushAllAsync(context.Background()).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().FlushAllAsync(ctx).Result()
 	return err
 }
 
-func (redisClient RedisClientWrapper) SetNXDataToCache(key string, value string, exprire time.Duration) (bool, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) FlushAllAsync() error {
+	return redisClient.FlushAllAsyncWithContext(context.Background())
+}
+
+func (redisClient RedisClientWrapper) SetNXDataToCacheWithContext(ctx context.Context, key string, value string, exprire time.Duration) (bool, error) {
+	if redisClient.universal() == nil {
 		return false, nilClientError
 	}
 
-	result, err := redisClient.Client.SetNX(context.Background(), key, value, exprire).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	result, err := redisClient.universal().SetNX(ctx, key, value, exprire).Result()
 	if err != nil {
 		return false, err
 	}
@@ -190,12 +297,19 @@ func (redisClient RedisClientWrapper) SetNXDataToCache(key string, value string,
 	return result, nil
 }
 
-func (redisClient RedisClientWrapper) DeleteDataFromKey(key string) error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) SetNXDataToCache(key string, value string, exprire time.Duration) (bool, error) {
+	return redisClient.SetNXDataToCacheWithContext(context.Background(), key, value, exprire)
+}
+
+func (redisClient RedisClientWrapper) DeleteDataFromKeyWithContext(ctx context.Context, key string) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.Del(context.Background(), key).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().Del(ctx, key).Result()
 	if err != nil {
 		return err
 	}
@@ -203,12 +317,19 @@ func (redisClient RedisClientWrapper) DeleteDataFromKey(key string) error {
 	return nil
 }
 
-func (redisClient RedisClientWrapper) ZScore(key string, member string) (*float64, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) DeleteDataFromKey(key string) error {
+	return redisClient.DeleteDataFromKeyWithContext(context.Background(), key)
+}
+
+func (redisClient RedisClientWrapper) ZScoreWithContext(ctx context.Context, key string, member string) (*float64, error) {
+	if redisClient.universal() == nil {
 		return nil, nilClientError
 	}
 
-	data, err := redisClient.Client.ZScore(context.Background(), key, member).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	data, err := redisClient.universal().ZScore(ctx, key, member).Result()
 	if err == redis.Nil {
 		return nil, nil
 	} else if err != nil {
@@ -218,12 +339,19 @@ func (redisClient RedisClientWrapper) ZScore(key string, member string) (*float6
 	return &data, nil
 }
 
-func (redisClient RedisClientWrapper) ZAdd(key string, member string, score float64) error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) ZScore(key string, member string) (*float64, error) {
+	return redisClient.ZScoreWithContext(context.Background(), key, member)
+}
+
+func (redisClient RedisClientWrapper) ZAddWithContext(ctx context.Context, key string, member string, score float64) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.ZAdd(context.Background(), key, redis.Z{Score: score, Member: member}).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Result()
 	if err != nil {
 		return err
 	}
@@ -231,12 +359,19 @@ func (redisClient RedisClientWrapper) ZAdd(key string, member string, score floa
 	return nil
 }
 
-func (redisClient RedisClientWrapper) ZRem(key string, members ...interface{}) error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) ZAdd(key string, member string, score float64) error {
+	return redisClient.ZAddWithContext(context.Background(), key, member, score)
+}
+
+func (redisClient RedisClientWrapper) ZRemWithContext(ctx context.Context, key string, members ...interface{}) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.ZRem(context.Background(), key, members).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().ZRem(ctx, key, members...).Result()
 	if err != nil {
 		return err
 	}
@@ -244,12 +379,19 @@ func (redisClient RedisClientWrapper) ZRem(key string, members ...interface{}) e
 	return nil
 }
 
-func (redisClient RedisClientWrapper) ZRangeByScore(key string, min float64, max float64) ([]string, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) ZRem(key string, members ...interface{}) error {
+	return redisClient.ZRemWithContext(context.Background(), key, members...)
+}
+
+func (redisClient RedisClientWrapper) ZRangeByScoreWithContext(ctx context.Context, key string, min float64, max float64) ([]string, error) {
+	if redisClient.universal() == nil {
 		return nil, nilClientError
 	}
 
-	components, err := redisClient.Client.ZRangeByScore(context.Background(), key, &redis.ZRangeBy{Min: strconv.FormatFloat(min, 'f', 0, 64), Max: strconv.FormatFloat(max, 'f', 0, 64)}).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	components, err := redisClient.universal().ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: strconv.FormatFloat(min, 'f', 0, 64), Max: strconv.FormatFloat(max, 'f', 0, 64)}).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -257,12 +399,20 @@ func (redisClient RedisClientWrapper) ZRangeByScore(key string, min float64, max
 	return components, nil
 }
 
-func (redisClient RedisClientWrapper) PublishToChannel(channelName string, message interface{}) error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) ZRangeByScore(key string, min float64, max float64) ([]string, error) {
+	return redisClient.ZRangeByScoreWithContext(context.Background(), key, min, max)
+}
+
+func (redisClient RedisClientWrapper) PublishToChannelWithContext(ctx context.Context, channelName string, message interface{}) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
 	// Publish a message
-	err := redisClient.Client.Publish(context.Background(), channelName, message).Err()
+	err := redisClient.universal().Publish(ctx, channelName, message).Err()
 	if err != nil {
 		return err
 	}
@@ -270,47 +420,106 @@ func (redisClient RedisClientWrapper) PublishToChannel(channelName string, messa
 	return nil
 }
 
-func (redisClient RedisClientWrapper) SubscribeChannel(channelName string) (*redis.PubSub, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) PublishToChannel(channelName string, message interface{}) error {
+	return redisClient.PublishToChannelWithContext(context.Background(), channelName, message)
+}
+
+// SubscribeChannelWithContext subscribes with ctx; DefaultTimeout is not applied since the subscription outlives the call
+func (redisClient RedisClientWrapper) SubscribeChannelWithContext(ctx context.Context, channelName string) (*redis.PubSub, error) {
+	if redisClient.universal() == nil {
 		return nil, nilClientError
 	}
 
-	return redisClient.Client.Subscribe(context.Background(), channelName), nil
+	return redisClient.universal().Subscribe(ctx, channelName), nil
+}
+
+func (redisClient RedisClientWrapper) SubscribeChannel(channelName string) (*redis.PubSub, error) {
+	return redisClient.SubscribeChannelWithContext(context.Background(), channelName)
+}
+
+func (redisClient RedisClientWrapper) UnlinkWithContext(ctx context.Context, keys ...string) error {
+	if redisClient.universal() == nil {
+		return nilClientError
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return redisClient.unlink(ctx, keys)
+}
+
+// unlink unlinks keys with one command, or one per key on a cluster where they may live on different nodes
+func (redisClient RedisClientWrapper) unlink(ctx context.Context, keys []string) error {
+	if !redisClient.isCluster() {
+		return redisClient.universal().Unlink(ctx, keys...).Err()
+	}
+
+	pipe := redisClient.universal().Pipeline()
+	for _, key := range keys {
+		pipe.Unlink(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 func (redisClient RedisClientWrapper) Unlink(keys ...string) error {
-	if redisClient.Client == nil {
+	return redisClient.UnlinkWithContext(context.Background(), keys...)
+}
+
+func (redisClient RedisClientWrapper) AddToSetWithContext(ctx context.Context, folder string, keys ...any) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.Unlink(context.Background(), keys...).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().SAdd(ctx, folder, keys...).Result()
 	return err
 }
 
 func (redisClient RedisClientWrapper) AddToSet(folder string, keys ...any) error {
-	if redisClient.Client == nil {
+	return redisClient.AddToSetWithContext(context.Background(), folder, keys...)
+}
+
+func (redisClient RedisClientWrapper) RemoveFromSetWithContext(ctx context.Context, folder string, keys ...any) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.SAdd(context.Background(), folder, keys...).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().SRem(ctx, folder, keys...).Result()
 	return err
 }
 
 func (redisClient RedisClientWrapper) RemoveFromSet(folder string, keys ...any) error {
-	if redisClient.Client == nil {
+	return redisClient.RemoveFromSetWithContext(context.Background(), folder, keys...)
+}
+
+// ExpireWithContext sets the TTL of key, e.g. of a set folder that should not outlive the keys it lists
+func (redisClient RedisClientWrapper) ExpireWithContext(ctx context.Context, key string, expire time.Duration) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.SRem(context.Background(), folder, keys...).Result()
-	return err
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return redisClient.universal().Expire(ctx, key, expire).Err()
 }
 
-func (redisClient RedisClientWrapper) RemoveASet(folder string) error {
-	if redisClient.Client == nil {
+// RemoveASetWithContext deletes the set folder and the keys it lists, DefaultTimeout applies to the whole operation
+func (redisClient RedisClientWrapper) RemoveASetWithContext(ctx context.Context, folder string) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	keys, err := redisClient.Client.SMembers(context.Background(), folder).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	keys, err := redisClient.universal().SMembers(ctx, folder).Result()
 	if err != nil {
 		return err
 	}
@@ -319,35 +528,32 @@ func (redisClient RedisClientWrapper) RemoveASet(folder string) error {
 		return nil
 	}
 
-	_keys := make([]interface{}, len(keys))
-	for i, key := range keys {
-		_keys[i] = key
-	}
-
-	_, err = redisClient.Client.Del(context.Background(), folder).Result()
-	if err != nil {
+	if err := redisClient.unlink(ctx, keys); err != nil {
 		return err
 	}
 
-	_, err = redisClient.Client.Unlink(context.Background(), keys...).Result()
+	// The folder goes last, so that a failure leaves it listing the keys for the next attempt
+	_, err = redisClient.universal().Del(ctx, folder).Result()
 	if err != nil {
 		return err
 	}
 
-	/*_, err = redisClient.Client.SRem(context.Background(), folder, _keys...).Result()
-	if err != nil {
-		return err
-	}*/
-
 	return nil
 }
 
-func (redisClient RedisClientWrapper) FlushDBAsync() error {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) RemoveASet(folder string) error {
+	return redisClient.RemoveASetWithContext(context.Background(), folder)
+}
+
+func (redisClient RedisClientWrapper) FlushDBAsyncWithContext(ctx context.Context) error {
+	if redisClient.universal() == nil {
 		return nilClientError
 	}
 
-	_, err := redisClient.Client.FlushDBAsync(context.Background()).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	_, err := redisClient.universal().FlushDBAsync(ctx).Result()
 	if err != nil {
 		return err
 	}
@@ -355,10 +561,21 @@ func (redisClient RedisClientWrapper) FlushDBAsync() error {
 	return nil
 }
 
-func (redisClient RedisClientWrapper) GetSetKeys(folder string) ([]string, error) {
-	if redisClient.Client == nil {
+func (redisClient RedisClientWrapper) FlushDBAsync() error {
+	return redisClient.FlushDBAsyncWithContext(context.Background())
+}
+
+func (redisClient RedisClientWrapper) GetSetKeysWithContext(ctx context.Context, folder string) ([]string, error) {
+	if redisClient.universal() == nil {
 		return nil, nilClientError
 	}
 
-	return redisClient.Client.SMembers(context.Background(), folder).Result()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return redisClient.universal().SMembers(ctx, folder).Result()
+}
+
+func (redisClient RedisClientWrapper) GetSetKeys(folder string) ([]string, error) {
+	return redisClient.GetSetKeysWithContext(context.Background(), folder)
 }
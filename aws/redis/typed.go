@@ -0,0 +1,142 @@
+package awsRedis
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"time"
+
+	"github.com/BeeTechHub/go-common/async"
+	"github.com/BeeTechHub/go-common/logger"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// loadGroup de-duplicates the concurrent GetOrLoad calls of a key on a client
+var loadGroup singleflight.Group
+
+func codecOf(codecs []Codec) Codec {
+	if len(codecs) > 0 && codecs[0] != nil {
+		return codecs[0]
+	}
+	return DefaultCodec
+}
+
+// SetJSON stores value under key encoded with codec, DefaultCodec when omitted
+func SetJSON[T any](ctx context.Context, redisClient RedisClientWrapper, key string, value T, expire time.Duration, codec ...Codec) error {
+	if redisClient.universal() == nil {
+		return nilClientError
+	}
+
+	data, err := codecOf(codec).Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	return redisClient.universal().Set(ctx, key, data, expire).Err()
+}
+
+// GetJSON returns the value stored under key by SetJSON with the same codec, nil when the key is missing
+func GetJSON[T any](ctx context.Context, redisClient RedisClientWrapper, key string, codec ...Codec) (*T, error) {
+	if redisClient.universal() == nil {
+		return nil, nilClientError
+	}
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	data, err := redisClient.universal().Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var value T
+	if err := codecOf(codec).Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// LoadOptions configures GetOrLoad. Zero values use the defaults.
+type LoadOptions struct {
+	// Codec defaults to DefaultCodec
+	Codec Codec
+	// Jitter adds up to Jitter * ttl to the TTL of a loaded value, so keys loaded together do not expire together (default 0.1, negative disables)
+	Jitter float64
+	// LoadTimeout bounds the shared loader call, which outlives the callers that stop waiting for it (default 30s)
+	LoadTimeout time.Duration
+}
+
+func jittered(ttl time.Duration, jitter float64) time.Duration {
+	if jitter == 0 {
+		jitter = 0.1
+	}
+	if jitter < 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*jitter*float64(ttl))
+}
+
+// GetOrLoad returns the value cached under key, or calls loader and caches its result for ttl.
+// Concurrent calls for a key on the same client share one loader call, which gets the values of the ctx of the first
+// caller but not its cancellation; each caller stops waiting when its ctx is done. Redis errors are logged and fall
+// back to loader, so a cache outage does not fail the callers.
+func GetOrLoad[T any](ctx context.Context, redisClient RedisClientWrapper, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOptions) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var opt LoadOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.LoadTimeout <= 0 {
+		opt.LoadTimeout = 30 * time.Second
+	}
+
+	cached, err := GetJSON[T](ctx, redisClient, key, opt.Codec)
+	if err != nil {
+		logger.Warnf("redis: get %s failed: %v", key, err)
+	} else if cached != nil {
+		return *cached, nil
+	}
+
+	// The client and the type are part of the key so that callers of different servers, or loading different types
+	// under one key, do not share results
+	flightKey := fmt.Sprintf("%p|%s|%T", redisClient.universal(), key, *new(T))
+	results := loadGroup.DoChan(flightKey, func() (result any, err error) {
+		// DoChan would re-panic in its own goroutine, out of reach of the callers
+		defer func() {
+			if r := recover(); r != nil {
+				err = &async.PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), opt.LoadTimeout)
+		defer cancel()
+
+		value, err := loader(loadCtx)
+		if err != nil {
+			return value, err
+		}
+
+		if err := SetJSON(loadCtx, redisClient, key, value, jittered(ttl, opt.Jitter), opt.Codec); err != nil {
+			logger.Warnf("redis: set %s failed: %v", key, err)
+		}
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case result := <-results:
+		value, _ := result.Val.(T)
+		return value, result.Err
+	}
+}
@@ -2,6 +2,7 @@ package awsSes
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"net/textproto"
 	"strings"
 
+	"github.com/BeeTechHub/go-common/async"
 	config "github.com/BeeTechHub/go-common/aws/config"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ses"
@@ -24,6 +26,8 @@ var svc *ses.SES
 type SesWrapper struct {
 	Ses         *ses.SES
 	EmailSender string
+	RetryPolicy async.RetryPolicy
+	breaker     *async.CircuitBreaker
 }
 
 func InitSes(emailSender string) SesWrapper {
@@ -32,7 +36,12 @@ func InitSes(emailSender string) SesWrapper {
 		svc = ses.New(sess)
 	}
 
-	return SesWrapper{svc, emailSender}
+	return SesWrapper{svc, emailSender, config.DefaultRetryPolicy(), nil}
+}
+
+// AttachCircuitBreaker makes send calls fail fast with *async.CircuitOpenError while breaker is open
+func (sesWrapper *SesWrapper) AttachCircuitBreaker(breaker *async.CircuitBreaker) {
+	sesWrapper.breaker = breaker
 }
 
 func (sesWrapper SesWrapper) SendEmail(recipient string, subject string, body string) (*ses.SendEmailOutput, error) {
@@ -70,7 +79,11 @@ func (sesWrapper SesWrapper) SendEmail(recipient string, subject string, body st
 	}
 
 	// Attempt to send the email.
-	result, err := sesWrapper.Ses.SendEmail(input)
+	result, err := async.Guard(sesWrapper.breaker, func() (*ses.SendEmailOutput, error) {
+		return async.Retry(context.Background(), sesWrapper.RetryPolicy, func(ctx context.Context) (*ses.SendEmailOutput, error) {
+			return sesWrapper.Ses.SendEmailWithContext(ctx, input, config.SingleAttempt)
+		})
+	})
 
 	// Display error messages if they occur.
 	if err != nil {
@@ -183,5 +196,7 @@ func (sesWrapper SesWrapper) SendRichEmail(recipients []string, subject string,
 		},
 	}
 
-	return sesWrapper.Ses.SendRawEmail(input)
+	return async.Guard(sesWrapper.breaker, func() (*ses.SendRawEmailOutput, error) {
+		return sesWrapper.Ses.SendRawEmail(input)
+	})
 }
@@ -1,9 +1,11 @@
 package awsSqs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"github.com/BeeTechHub/go-common/async"
 	config "github.com/BeeTechHub/go-common/aws/config"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -20,6 +22,8 @@ type SqsWrapper struct {
 	MaxNumberOfMsg    int64
 	WaitTime          int64
 	VisibilityTimeout int64
+	RetryPolicy       async.RetryPolicy
+	breaker           *async.CircuitBreaker
 }
 
 // queueName: Tên queue
@@ -69,18 +73,47 @@ func InitSqs(queueName string, delaySeconds, maxNumberOfMsg, waitTime, visibilit
 		maxNumberOfMsg = 10
 	}
 
-	return &SqsWrapper{svc, *queueUrl, delaySeconds, maxNumberOfMsg, waitTime, visibilityTimeout}, nil
+	return &SqsWrapper{svc, *queueUrl, delaySeconds, maxNumberOfMsg, waitTime, visibilityTimeout, config.DefaultRetryPolicy(), nil}, nil
+}
+
+// AttachCircuitBreaker makes send, pull and delete calls fail fast with *async.CircuitOpenError while breaker is open
+func (sqsWrapper *SqsWrapper) AttachCircuitBreaker(breaker *async.CircuitBreaker) {
+	sqsWrapper.breaker = breaker
 }
 
 func (sqsWrapper SqsWrapper) SendStandardMsg(msg string) (*sqs.SendMessageOutput, error) {
+	return sqsWrapper.SendMsgWithContext(context.Background(), msg, nil)
+}
+
+// SendMsgWithContext sends msg with the non-empty attributes as String message attributes, retrying until ctx is done
+func (sqsWrapper SqsWrapper) SendMsgWithContext(ctx context.Context, msg string, attributes map[string]string) (*sqs.SendMessageOutput, error) {
 	if sqsWrapper.Sqs == nil {
 		return nil, nilSqsError
 	}
 
-	result, err := sqsWrapper.Sqs.SendMessage(&sqs.SendMessageInput{
+	input := &sqs.SendMessageInput{
 		DelaySeconds: &sqsWrapper.DelaySeconds,
 		MessageBody:  aws.String(msg),
 		QueueUrl:     &sqsWrapper.QueueUrl,
+	}
+	if len(attributes) > 0 {
+		input.MessageAttributes = make(map[string]*sqs.MessageAttributeValue, len(attributes))
+		for name, value := range attributes {
+			// SQS rejects empty attribute values
+			if value == "" {
+				continue
+			}
+			input.MessageAttributes[name] = &sqs.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(value),
+			}
+		}
+	}
+
+	result, err := async.Guard(sqsWrapper.breaker, func() (*sqs.SendMessageOutput, error) {
+		return async.Retry(ctx, sqsWrapper.RetryPolicy, func(ctx context.Context) (*sqs.SendMessageOutput, error) {
+			return sqsWrapper.Sqs.SendMessageWithContext(ctx, input, config.SingleAttempt)
+		})
 	})
 
 	if err != nil {
@@ -95,14 +128,16 @@ func (sqsWrapper SqsWrapper) PullMessages() ([]*sqs.Message, error) {
 		return nil, nilSqsError
 	}
 
-	results, err := svc.ReceiveMessage(&sqs.ReceiveMessageInput{
-		QueueUrl: &sqsWrapper.QueueUrl,
-		MessageAttributeNames: aws.StringSlice([]string{
-			"All",
-		}),
-		MaxNumberOfMessages: &sqsWrapper.MaxNumberOfMsg,
-		WaitTimeSeconds:     &sqsWrapper.WaitTime,
-		VisibilityTimeout:   &sqsWrapper.VisibilityTimeout,
+	results, err := async.Guard(sqsWrapper.breaker, func() (*sqs.ReceiveMessageOutput, error) {
+		return svc.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl: &sqsWrapper.QueueUrl,
+			MessageAttributeNames: aws.StringSlice([]string{
+				"All",
+			}),
+			MaxNumberOfMessages: &sqsWrapper.MaxNumberOfMsg,
+			WaitTimeSeconds:     &sqsWrapper.WaitTime,
+			VisibilityTimeout:   &sqsWrapper.VisibilityTimeout,
+		})
 	})
 	// snippet-end:[sqs.go.send_receive_long_polling.call2]
 	if err != nil {
@@ -117,9 +152,11 @@ func (sqsWrapper SqsWrapper) DeleteMessage(message *sqs.Message) (*sqs.DeleteMes
 		return nil, nilSqsError
 	}
 
-	result, err := svc.DeleteMessage(&sqs.DeleteMessageInput{
-		QueueUrl:      &sqsWrapper.QueueUrl,
-		ReceiptHandle: message.ReceiptHandle,
+	result, err := async.Guard(sqsWrapper.breaker, func() (*sqs.DeleteMessageOutput, error) {
+		return svc.DeleteMessage(&sqs.DeleteMessageInput{
+			QueueUrl:      &sqsWrapper.QueueUrl,
+			ReceiptHandle: message.ReceiptHandle,
+		})
 	})
 
 	if err != nil {
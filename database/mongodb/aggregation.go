@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"github.com/BeeTechHub/go-common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type facetPage struct {
+	Data  bson.RawValue `bson:"data"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+// AggregatePaginated runs pipeline and returns one page of its output with the total count, in a single round trip.
+// The count is taken after every stage of pipeline, unlike a CountDocuments on the $match filter alone.
+// The page itself must fit in a 16MB document, as it is returned through $facet.
+func (collection MongoCollectionWrapper) AggregatePaginated(sessContext mongo.SessionContext, records any, page int64, size int64, pipeline []bson.M, sortParams bson.D, opts ...*options.AggregateOptions) (int64, error) {
+	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
+	defer cancel()
+
+	if len(sortParams) == 0 {
+		sortParams = bson.D{{Key: "_id", Value: -1}}
+	}
+
+	facet := bson.M{
+		"$facet": bson.M{
+			"data": bson.A{
+				bson.M{"$sort": sortParams},
+				bson.M{"$skip": utils.CalculatePaginatedSkip(page, size)},
+				bson.M{"$limit": size},
+			},
+			"total": bson.A{
+				bson.M{"$count": "count"},
+			},
+		},
+	}
+
+	scoped := collection.scopePipeline(pipeline)
+	fullPipeline := make([]bson.M, 0, len(scoped)+1)
+	fullPipeline = append(append(fullPipeline, scoped...), facet)
+
+	cursor, err := collection.Collection.Aggregate(ctx, fullPipeline, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		return 0, cursor.Err()
+	}
+
+	var result facetPage
+	if err := cursor.Decode(&result); err != nil {
+		return 0, err
+	}
+
+	if len(result.Total) == 0 || result.Total[0].Count == 0 {
+		return 0, nil
+	}
+
+	if err := result.Data.Unmarshal(records); err != nil {
+		return 0, err
+	}
+
+	return result.Total[0].Count, nil
+}
@@ -0,0 +1,294 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditOptions enables automatic audit fields and soft delete on a collection, see MongoCollectionWrapper.WithAudit
+type AuditOptions struct {
+	// AuditFields stamps createdAt/updatedAt on writes, and createdBy/updatedBy when an actor is set with WithActor
+	AuditFields bool
+	// SoftDelete turns deletes into a deletedAt update and hides deleted documents from reads and updates
+	SoftDelete bool
+	// Now returns the time used for stamps (default time.Now)
+	Now func() time.Time
+
+	// Field names, defaulting to createdAt, updatedAt, createdBy, updatedBy and deletedAt
+	CreatedAtField string
+	UpdatedAtField string
+	CreatedByField string
+	UpdatedByField string
+	DeletedAtField string
+}
+
+type auditConfig struct {
+	AuditOptions
+	actor    any
+	unscoped bool
+}
+
+func withAuditDefaults(opts AuditOptions) AuditOptions {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.CreatedAtField == "" {
+		opts.CreatedAtField = "createdAt"
+	}
+	if opts.UpdatedAtField == "" {
+		opts.UpdatedAtField = "updatedAt"
+	}
+	if opts.CreatedByField == "" {
+		opts.CreatedByField = "createdBy"
+	}
+	if opts.UpdatedByField == "" {
+		opts.UpdatedByField = "updatedBy"
+	}
+	if opts.DeletedAtField == "" {
+		opts.DeletedAtField = "deletedAt"
+	}
+	return opts
+}
+
+// WithAudit returns a copy of the collection that stamps audit fields and/or soft deletes according to opts.
+// BulkWrite is passed through unchanged.
+func (collection MongoCollectionWrapper) WithAudit(opts AuditOptions) MongoCollectionWrapper {
+	collection.audit = &auditConfig{AuditOptions: withAuditDefaults(opts)}
+	return collection
+}
+
+// WithActor returns a copy of the collection that stamps actor in createdBy/updatedBy, typically one per request
+func (collection MongoCollectionWrapper) WithActor(actor any) MongoCollectionWrapper {
+	if collection.audit != nil {
+		audit := *collection.audit
+		audit.actor = actor
+		collection.audit = &audit
+	}
+	return collection
+}
+
+// Unscoped returns a copy of the collection that sees soft-deleted documents and deletes permanently
+func (collection MongoCollectionWrapper) Unscoped() MongoCollectionWrapper {
+	if collection.audit != nil {
+		audit := *collection.audit
+		audit.unscoped = true
+		collection.audit = &audit
+	}
+	return collection
+}
+
+func (collection MongoCollectionWrapper) softDeletes() bool {
+	return collection.audit != nil && collection.audit.SoftDelete && !collection.audit.unscoped
+}
+
+func (collection MongoCollectionWrapper) stampsAuditFields() bool {
+	return collection.audit != nil && collection.audit.AuditFields
+}
+
+// scopeFilter excludes soft-deleted documents, unless filter already mentions the deletedAt field
+func (collection MongoCollectionWrapper) scopeFilter(filter bson.M) bson.M {
+	if !collection.softDeletes() {
+		return filter
+	}
+
+	deletedAtField := collection.audit.DeletedAtField
+	if _, ok := filter[deletedAtField]; ok {
+		return filter
+	}
+
+	scoped := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		scoped[k] = v
+	}
+	scoped[deletedAtField] = nil
+	return scoped
+}
+
+// firstStages must start a pipeline, so the soft-delete $match goes after them
+var firstStages = []string{"$geoNear", "$search", "$searchMeta", "$vectorSearch", "$collStats", "$indexStats"}
+
+func isFirstStage(stage bson.M) bool {
+	for _, name := range firstStages {
+		if _, ok := stage[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (collection MongoCollectionWrapper) scopePipeline(pipeline []bson.M) []bson.M {
+	if !collection.softDeletes() {
+		return pipeline
+	}
+
+	i := 0
+	if len(pipeline) > 0 && isFirstStage(pipeline[0]) {
+		i = 1
+	}
+
+	scoped := make([]bson.M, 0, len(pipeline)+1)
+	scoped = append(scoped, pipeline[:i]...)
+	scoped = append(scoped, bson.M{"$match": collection.scopeFilter(bson.M{})})
+	return append(scoped, pipeline[i:]...)
+}
+
+// toDocument converts a record or an update operator value to a bson.D
+func toDocument(value any) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	err = bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+func setField(doc bson.D, key string, value any) bson.D {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: key, Value: value})
+}
+
+func lookupField(doc bson.D, key string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func hasField(doc bson.D, key string) bool {
+	_, ok := lookupField(doc, key)
+	return ok
+}
+
+// updatedFields are the fields set on every update
+func (collection MongoCollectionWrapper) updatedFields(now time.Time) bson.D {
+	fields := bson.D{{Key: collection.audit.UpdatedAtField, Value: now}}
+	if collection.audit.actor != nil {
+		fields = append(fields, bson.E{Key: collection.audit.UpdatedByField, Value: collection.audit.actor})
+	}
+	return fields
+}
+
+// createdFields are the fields set when a document is created
+func (collection MongoCollectionWrapper) createdFields(now time.Time) bson.D {
+	fields := bson.D{{Key: collection.audit.CreatedAtField, Value: now}}
+	if collection.audit.actor != nil {
+		fields = append(fields, bson.E{Key: collection.audit.CreatedByField, Value: collection.audit.actor})
+	}
+	return fields
+}
+
+// stampInsert returns record as a document with created and updated fields set
+func (collection MongoCollectionWrapper) stampInsert(record any) (any, error) {
+	if !collection.stampsAuditFields() {
+		return record, nil
+	}
+
+	doc, err := toDocument(record)
+	if err != nil {
+		return nil, err
+	}
+
+	now := collection.audit.Now()
+	for _, e := range append(collection.createdFields(now), collection.updatedFields(now)...) {
+		doc = setField(doc, e.Key, e.Value)
+	}
+	return doc, nil
+}
+
+// stampReplace returns a replacement document with updated fields set
+func (collection MongoCollectionWrapper) stampReplace(record any) (any, error) {
+	if !collection.stampsAuditFields() {
+		return record, nil
+	}
+
+	doc, err := toDocument(record)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range collection.updatedFields(collection.audit.Now()) {
+		doc = setField(doc, e.Key, e.Value)
+	}
+	return doc, nil
+}
+
+// stampUpdate adds updated fields to $set and created fields to $setOnInsert, without modifying update
+func (collection MongoCollectionWrapper) stampUpdate(update bson.M) (bson.M, error) {
+	if !collection.stampsAuditFields() {
+		return update, nil
+	}
+
+	set, err := toDocument(update["$set"])
+	if err != nil {
+		return nil, err
+	}
+	setOnInsert, err := toDocument(update["$setOnInsert"])
+	if err != nil {
+		return nil, err
+	}
+
+	now := collection.audit.Now()
+	for _, e := range collection.updatedFields(now) {
+		set = setField(set, e.Key, e.Value)
+	}
+	for _, e := range collection.createdFields(now) {
+		// A field cannot be in both $set and $setOnInsert
+		if !hasField(set, e.Key) && !hasField(setOnInsert, e.Key) {
+			setOnInsert = append(setOnInsert, e)
+		}
+	}
+
+	stamped := make(bson.M, len(update)+2)
+	for k, v := range update {
+		stamped[k] = v
+	}
+	stamped["$set"] = set
+	if len(setOnInsert) > 0 {
+		stamped["$setOnInsert"] = setOnInsert
+	}
+	return stamped, nil
+}
+
+// softDeleteOptions keeps the options of a delete that apply to the update soft-deleting instead
+func softDeleteOptions(opts []*options.DeleteOptions) *options.UpdateOptions {
+	deleteOpts := options.MergeDeleteOptions(opts...)
+	updateOpts := options.Update()
+	if deleteOpts.Collation != nil {
+		updateOpts.SetCollation(deleteOpts.Collation)
+	}
+	if deleteOpts.Hint != nil {
+		updateOpts.SetHint(deleteOpts.Hint)
+	}
+	if deleteOpts.Comment != nil {
+		updateOpts.SetComment(deleteOpts.Comment)
+	}
+	if deleteOpts.Let != nil {
+		updateOpts.SetLet(deleteOpts.Let)
+	}
+	return updateOpts
+}
+
+// softDeleteUpdate is the update applied instead of deleting documents
+func (collection MongoCollectionWrapper) softDeleteUpdate() bson.M {
+	now := collection.audit.Now()
+	set := bson.D{{Key: collection.audit.DeletedAtField, Value: now}}
+	if collection.stampsAuditFields() {
+		set = append(set, collection.updatedFields(now)...)
+	}
+	return bson.M{"$set": set}
+}
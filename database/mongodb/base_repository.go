@@ -23,74 +23,109 @@ func (collection MongoCollectionWrapper) Count(sessContext mongo.SessionContext,
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.CountDocuments(ctx, filter, opts...)
+	return collection.Collection.CountDocuments(ctx, collection.scopeFilter(filter), opts...)
 }
 
 func (collection MongoCollectionWrapper) FindOne(sessContext mongo.SessionContext, record any, filter bson.M, opts ...*options.FindOneOptions) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.FindOne(ctx, filter, opts...).Decode(record)
+	return collection.Collection.FindOne(ctx, collection.scopeFilter(filter), opts...).Decode(record)
 }
 
 func (collection MongoCollectionWrapper) FindMany(sessContext mongo.SessionContext, records any, filter bson.M, opts ...*options.FindOptions) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	cursor, err := collection.Collection.Find(ctx, filter, opts...)
+	cursor, err := collection.Collection.Find(ctx, collection.scopeFilter(filter), opts...)
 	if err != nil {
 		return err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	return cursor.All(context.TODO(), records)
+	return cursor.All(ctx, records)
 }
 
 func (collection MongoCollectionWrapper) FindManyWithAggregation(sessContext mongo.SessionContext, records any, pipeline []bson.M, opts ...*options.AggregateOptions) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	cursor, err := collection.Collection.Aggregate(ctx, pipeline, opts...)
+	cursor, err := collection.Collection.Aggregate(ctx, collection.scopePipeline(pipeline), opts...)
 	if err != nil {
 		return err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	return cursor.All(context.TODO(), records)
+	return cursor.All(ctx, records)
 }
 
 func (collection MongoCollectionWrapper) UpdateOne(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.UpdateOne(ctx, filter, update, opts...)
+	stampedUpdate, err := collection.stampUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection.Collection.UpdateOne(ctx, collection.scopeFilter(filter), stampedUpdate, opts...)
 }
 
 func (collection MongoCollectionWrapper) UpdateMany(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.UpdateMany(ctx, filter, update, opts...)
+	stampedUpdate, err := collection.stampUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection.Collection.UpdateMany(ctx, collection.scopeFilter(filter), stampedUpdate, opts...)
 }
 
 func (collection MongoCollectionWrapper) InsertOne(sessContext mongo.SessionContext, newRecord interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.InsertOne(ctx, newRecord, opts...)
+	stampedRecord, err := collection.stampInsert(newRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return collection.Collection.InsertOne(ctx, stampedRecord, opts...)
 }
 
 func (collection MongoCollectionWrapper) InsertMany(sessContext mongo.SessionContext, newRecords []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.InsertMany(ctx, newRecords, opts...)
+	stampedRecords := newRecords
+	if collection.stampsAuditFields() {
+		stampedRecords = make([]interface{}, len(newRecords))
+		for i, newRecord := range newRecords {
+			stampedRecord, err := collection.stampInsert(newRecord)
+			if err != nil {
+				return nil, err
+			}
+			stampedRecords[i] = stampedRecord
+		}
+	}
+
+	return collection.Collection.InsertMany(ctx, stampedRecords, opts...)
 }
 
 func (collection MongoCollectionWrapper) DeleteMany(sessContext mongo.SessionContext, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
+	if collection.softDeletes() {
+		result, err := collection.Collection.UpdateMany(ctx, collection.scopeFilter(filter), collection.softDeleteUpdate(), softDeleteOptions(opts))
+		if err != nil {
+			return nil, err
+		}
+		return &mongo.DeleteResult{DeletedCount: result.ModifiedCount}, nil
+	}
+
 	return collection.Collection.DeleteMany(ctx, filter, opts...)
 }
 
@@ -105,13 +140,18 @@ func (collection MongoCollectionWrapper) FindOneById(sessContext mongo.SessionCo
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(record)
+	return collection.Collection.FindOne(ctx, collection.scopeFilter(bson.M{"_id": id})).Decode(record)
 }
 
 func (collection MongoCollectionWrapper) DeleteOneById(sessContext mongo.SessionContext, id primitive.ObjectID) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
+	if collection.softDeletes() {
+		_, err := collection.Collection.UpdateMany(ctx, collection.scopeFilter(bson.M{"_id": id}), collection.softDeleteUpdate())
+		return err
+	}
+
 	_, err := collection.Collection.DeleteMany(ctx, bson.M{"_id": id})
 	return err
 }
@@ -120,34 +160,47 @@ func (collection MongoCollectionWrapper) FindManyByIds(sessContext mongo.Session
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	cursor, err := collection.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts...)
+	cursor, err := collection.Collection.Find(ctx, collection.scopeFilter(bson.M{"_id": bson.M{"$in": ids}}), opts...)
 	if err != nil {
 		return err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	return cursor.All(context.TODO(), records)
+	return cursor.All(ctx, records)
 }
 
 func (collection MongoCollectionWrapper) DeleteManyByIds(sessContext mongo.SessionContext, ids []primitive.ObjectID) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
+	if collection.softDeletes() {
+		_, err := collection.Collection.UpdateMany(ctx, collection.scopeFilter(bson.M{"_id": bson.M{"$in": ids}}), collection.softDeleteUpdate())
+		return err
+	}
+
 	_, err := collection.Collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	return err
 }
 
+// FindPaginated returns one page of the documents matching filter with their total count.
+// When pipe is given, the count is taken after its stages with AggregatePaginated.
 func (collection MongoCollectionWrapper) FindPaginated(sessContext mongo.SessionContext, records any, page int64, size int64, filter bson.M, sortParams bson.D, pipe ...bson.M) (int64, error) {
+	if len(pipe) > 0 {
+		pipeline := append([]bson.M{{"$match": filter}}, pipe...)
+		return collection.AggregatePaginated(sessContext, records, page, size, pipeline, sortParams)
+	}
+
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
+	filter = collection.scopeFilter(filter)
 	matchFilter := bson.M{
 		"$match": filter,
 	}
 
 	if len(sortParams) == 0 {
 		sortParams = bson.D{
-			{"_id", -1},
+			{Key: "_id", Value: -1},
 		}
 	}
 
@@ -163,13 +216,7 @@ func (collection MongoCollectionWrapper) FindPaginated(sessContext mongo.Session
 		"$skip": utils.CalculatePaginatedSkip(page, size),
 	}
 
-	pipeline := []bson.M{matchFilter}
-
-	if len(pipe) > 0 {
-		pipeline = append(pipeline, pipe...)
-	}
-
-	pipeline = append(pipeline, sort, skip, limit)
+	pipeline := []bson.M{matchFilter, sort, skip, limit}
 
 	count, err := collection.Collection.CountDocuments(ctx, filter)
 	if err != nil {
@@ -182,9 +229,9 @@ func (collection MongoCollectionWrapper) FindPaginated(sessContext mongo.Session
 	if err != nil {
 		return 0, err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
-	err = cursor.All(context.TODO(), records)
+	err = cursor.All(ctx, records)
 	if err != nil {
 		return 0, err
 	}
@@ -196,20 +243,35 @@ func (collection MongoCollectionWrapper) FindOneAndUpdate(sessContext mongo.Sess
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.FindOneAndUpdate(ctx, filter, update, opts...).Decode(record)
+	stampedUpdate, err := collection.stampUpdate(update)
+	if err != nil {
+		return err
+	}
+
+	return collection.Collection.FindOneAndUpdate(ctx, collection.scopeFilter(filter), stampedUpdate, opts...).Decode(record)
 }
 
 func (collection MongoCollectionWrapper) FindOneAndReplace(sessContext mongo.SessionContext, returnRecord any, filter bson.M, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	return collection.Collection.FindOneAndReplace(ctx, filter, updateRecord, opts...).Decode(returnRecord)
+	stampedRecord, err := collection.stampReplace(updateRecord)
+	if err != nil {
+		return err
+	}
+
+	return collection.Collection.FindOneAndReplace(ctx, collection.scopeFilter(filter), stampedRecord, opts...).Decode(returnRecord)
 }
 
 func (collection MongoCollectionWrapper) ReplaceOne(sessContext mongo.SessionContext, filter bson.M, updateRecord any, opts ...*options.ReplaceOptions) error {
 	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
 	defer cancel()
 
-	_, err := collection.Collection.ReplaceOne(ctx, filter, updateRecord, opts...)
+	stampedRecord, err := collection.stampReplace(updateRecord)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.Collection.ReplaceOne(ctx, collection.scopeFilter(filter), stampedRecord, opts...)
 	return err
 }
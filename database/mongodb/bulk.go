@@ -0,0 +1,197 @@
+package mongodb
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	mongoWrites "github.com/BeeTechHub/go-common/database/mongodb/internal/writes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
+)
+
+// BulkOptions configures InsertManyChunked and UpsertManyBy. Zero values use the defaults.
+type BulkOptions struct {
+	// ChunkSize is the number of documents sent per call (default 1000)
+	ChunkSize int
+	// Parallelism is the number of chunks written concurrently (default 1, forced to 1 inside a session)
+	Parallelism int
+}
+
+// BulkFailure is a document that could not be written
+type BulkFailure struct {
+	// Index is the position of the document in the input
+	Index int
+	// Code is the server error code, e.g. 11000 for a duplicate key, or 0 when the whole chunk failed
+	Code int
+	Err  error
+}
+
+// BulkReport sums up the results of the chunks. Failures are sorted by Index.
+type BulkReport struct {
+	Inserted int64
+	Matched  int64
+	Modified int64
+	Upserted int64
+	Failures []BulkFailure
+}
+
+func (report *BulkReport) add(other BulkReport) {
+	report.Inserted += other.Inserted
+	report.Matched += other.Matched
+	report.Modified += other.Modified
+	report.Upserted += other.Upserted
+	report.Failures = append(report.Failures, other.Failures...)
+}
+
+// chunkFailures converts the error of the chunk starting at offset with size documents into failures.
+// A BulkWriteException yields one failure per document; any other error fails the whole chunk and is returned.
+func chunkFailures(err error, offset int, size int) ([]BulkFailure, error) {
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		failures := make([]BulkFailure, len(bulkErr.WriteErrors))
+		for i, writeErr := range bulkErr.WriteErrors {
+			failures[i] = BulkFailure{Index: offset + writeErr.Index, Code: writeErr.Code, Err: writeErr}
+		}
+		if bulkErr.WriteConcernError != nil {
+			return failures, bulkErr.WriteConcernError
+		}
+		return failures, nil
+	}
+
+	failures := make([]BulkFailure, size)
+	for i := range failures {
+		failures[i] = BulkFailure{Index: offset + i, Err: err}
+	}
+	return failures, err
+}
+
+// writeChunks calls write for each chunk of n documents, up to opts.Parallelism at a time, and merges the reports.
+// The error joins the errors of the chunks that failed as a whole; per-document write errors are only in the report.
+func writeChunks(sessContext mongo.SessionContext, n int, opts []BulkOptions, write func(start int, end int) (BulkReport, error)) (BulkReport, error) {
+	var opt BulkOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.ChunkSize <= 0 {
+		opt.ChunkSize = 1000
+	}
+	if opt.Parallelism <= 0 || sessContext != nil {
+		// A session cannot be used concurrently
+		opt.Parallelism = 1
+	}
+
+	chunks := (n + opt.ChunkSize - 1) / opt.ChunkSize
+	reports := make([]BulkReport, chunks)
+	errs := make([]error, chunks)
+
+	group := errgroup.Group{}
+	group.SetLimit(opt.Parallelism)
+	for i := 0; i < chunks; i++ {
+		start := i * opt.ChunkSize
+		end := min(start+opt.ChunkSize, n)
+		group.Go(func() error {
+			reports[i], errs[i] = write(start, end)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	report := BulkReport{}
+	for _, chunkReport := range reports {
+		report.add(chunkReport)
+	}
+	return report, errors.Join(errs...)
+}
+
+// InsertManyChunked inserts records in unordered chunks, so a bad document only fails itself.
+// Each chunk gets its own timeout when sessContext is nil.
+func (collection MongoCollectionWrapper) InsertManyChunked(sessContext mongo.SessionContext, records []interface{}, opts ...BulkOptions) (BulkReport, error) {
+	return writeChunks(sessContext, len(records), opts, func(start int, end int) (BulkReport, error) {
+		report := BulkReport{}
+		_, err := collection.InsertMany(sessContext, records[start:end], options.InsertMany().SetOrdered(false))
+		if err == nil {
+			report.Inserted = int64(end - start)
+			return report, nil
+		}
+
+		report.Failures, err = chunkFailures(err, start, end-start)
+		report.Inserted = int64(end - start - len(report.Failures))
+		return report, err
+	})
+}
+
+// upsertModel is mongoWrites.UpsertModel with the audit fields stamped and the filter scoped to documents not soft-deleted
+func (collection MongoCollectionWrapper) upsertModel(keyFields []string, record any) (mongo.WriteModel, error) {
+	filter, update, err := mongoWrites.UpsertModel(keyFields, record)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.stampsAuditFields() {
+		// The created fields of the record are replaced by the ones stamped in $setOnInsert
+		set := bson.D{}
+		for _, e := range update["$set"].(bson.D) {
+			if e.Key != collection.audit.CreatedAtField && e.Key != collection.audit.CreatedByField {
+				set = append(set, e)
+			}
+		}
+		update["$set"] = set
+	}
+	stampedUpdate, err := collection.stampUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return mongo.NewUpdateOneModel().SetFilter(collection.scopeFilter(filter)).SetUpdate(stampedUpdate).SetUpsert(true), nil
+}
+
+// UpsertManyBy updates the document having the same keyFields values as each record with its fields, or inserts it.
+// Records are written in unordered chunks, so a bad document only fails itself. keyFields should be covered by a unique index.
+func (collection MongoCollectionWrapper) UpsertManyBy(sessContext mongo.SessionContext, keyFields []string, records []interface{}, opts ...BulkOptions) (BulkReport, error) {
+	if len(keyFields) == 0 {
+		return BulkReport{}, errors.New("mongodb: UpsertManyBy needs at least one key field")
+	}
+
+	return writeChunks(sessContext, len(records), opts, func(start int, end int) (BulkReport, error) {
+		report := BulkReport{}
+
+		// Records that cannot be converted fail alone, the others are sent
+		models := make([]mongo.WriteModel, 0, end-start)
+		positions := make([]int, 0, end-start)
+		for i := start; i < end; i++ {
+			model, err := collection.upsertModel(keyFields, records[i])
+			if err != nil {
+				report.Failures = append(report.Failures, BulkFailure{Index: i, Err: err})
+				continue
+			}
+			models = append(models, model)
+			positions = append(positions, i)
+		}
+		if len(models) == 0 {
+			return report, nil
+		}
+
+		result, err := collection.BulkWrite(sessContext, models, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			report.Matched = result.MatchedCount
+			report.Modified = result.ModifiedCount
+			report.Upserted = result.UpsertedCount
+		}
+		if err == nil {
+			return report, nil
+		}
+
+		failures, err := chunkFailures(err, 0, len(models))
+		for _, failure := range failures {
+			failure.Index = positions[failure.Index]
+			report.Failures = append(report.Failures, failure)
+		}
+		slices.SortFunc(report.Failures, func(a BulkFailure, b BulkFailure) int {
+			return cmp.Compare(a.Index, b.Index)
+		})
+		return report, err
+	})
+}
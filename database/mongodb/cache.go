@@ -0,0 +1,715 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	awsRedis "github.com/BeeTechHub/go-common/aws/redis"
+	mongoWrites "github.com/BeeTechHub/go-common/database/mongodb/internal/writes"
+	"github.com/BeeTechHub/go-common/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/singleflight"
+)
+
+// notFoundValue is cached for ids without a document; an encoded bson document is never empty
+const notFoundValue = ""
+
+// CacheOptions configures NewCachedCollection. Zero values use the defaults.
+type CacheOptions struct {
+	Redis *awsRedis.RedisClientWrapper
+	// KeyTemplate is the key of a cached document, "{id}" is replaced with its hex id (default "<database>:<collection>:{id}")
+	KeyTemplate string
+	// Folder is the redis set holding every key written by the cache, removed by InvalidateAll (default "<database>:<collection>").
+	// It expires max(TTL, NegativeTTL) after the last key was added, so that it does not outlive the keys it lists.
+	Folder string
+	// TTL is the lifetime of a cached document (default 10m)
+	TTL time.Duration
+	// NegativeTTL is the lifetime of a cached miss (default 1m, negative disables caching misses)
+	NegativeTTL time.Duration
+}
+
+type collectionCache struct {
+	CacheOptions
+	group singleflight.Group
+	// softDeletes is the scope of the cached documents, as read by the collection passed to NewCachedCollection
+	softDeletes bool
+}
+
+// CachedCollectionWrapper caches FindOneById and FindManyByIds in redis and invalidates the cached documents
+// on writes made through it. Single-document writes with a filter that is not on _id run as a findAndModify
+// returning the _id of the document, so that only writes that may touch any document, e.g. UpdateMany with
+// such a filter, invalidate every cached document. Writes made elsewhere, e.g. by another service, are visible once TTL expires.
+// Reads with a session context bypass the cache so that transactions see their own writes, and writes inside
+// RunInTransaction invalidate once the transaction commits.
+type CachedCollectionWrapper struct {
+	MongoCollectionWrapper
+	cache *collectionCache
+}
+
+func NewCachedCollection(collection MongoCollectionWrapper, opts CacheOptions) CachedCollectionWrapper {
+	name := collection.Collection.Database().Name() + ":" + collection.Collection.Name()
+	if opts.KeyTemplate == "" {
+		opts.KeyTemplate = name + ":{id}"
+	}
+	if opts.Folder == "" {
+		opts.Folder = name
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 10 * time.Minute
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = time.Minute
+	}
+
+	return CachedCollectionWrapper{collection, &collectionCache{CacheOptions: opts, softDeletes: collection.softDeletes()}}
+}
+
+// WithAudit is MongoCollectionWrapper.WithAudit keeping the cache
+func (collection CachedCollectionWrapper) WithAudit(opts AuditOptions) CachedCollectionWrapper {
+	return CachedCollectionWrapper{collection.MongoCollectionWrapper.WithAudit(opts), collection.cache}
+}
+
+// WithActor is MongoCollectionWrapper.WithActor keeping the cache
+func (collection CachedCollectionWrapper) WithActor(actor any) CachedCollectionWrapper {
+	return CachedCollectionWrapper{collection.MongoCollectionWrapper.WithActor(actor), collection.cache}
+}
+
+// Unscoped is MongoCollectionWrapper.Unscoped keeping the cache for invalidation; its reads bypass the cache
+func (collection CachedCollectionWrapper) Unscoped() CachedCollectionWrapper {
+	return CachedCollectionWrapper{collection.MongoCollectionWrapper.Unscoped(), collection.cache}
+}
+
+// WithTimeout is MongoCollectionWrapper.WithTimeout keeping the cache
+func (collection CachedCollectionWrapper) WithTimeout(timeout time.Duration) CachedCollectionWrapper {
+	return CachedCollectionWrapper{collection.MongoCollectionWrapper.WithTimeout(timeout), collection.cache}
+}
+
+// readsCache reports whether reads can use the cache, which holds documents of one soft-delete scope
+func (collection CachedCollectionWrapper) readsCache(sessContext mongo.SessionContext) bool {
+	return sessContext == nil && collection.softDeletes() == collection.cache.softDeletes
+}
+
+// afterWrite runs invalidate now, or after the commit of the transaction of sessContext so that
+// reads outside the transaction cannot cache the documents it is about to change
+func (collection CachedCollectionWrapper) afterWrite(sessContext mongo.SessionContext, invalidate func()) {
+	if !AfterCommit(sessContext, invalidate) {
+		invalidate()
+	}
+}
+
+func (cache *collectionCache) key(id primitive.ObjectID) string {
+	return strings.ReplaceAll(cache.KeyTemplate, "{id}", id.Hex())
+}
+
+// get returns the cached values of keys, nil for the missing ones
+func (cache *collectionCache) get(keys []string) ([]*string, error) {
+	if cache.Redis == nil {
+		return nil, errors.New("mongodb: cache redis client is nil")
+	}
+
+	return cache.Redis.GetValuesFromKeysWithContext(context.Background(), keys)
+}
+
+func (cache *collectionCache) set(key string, doc bson.Raw) {
+	value, ttl := string(doc), cache.TTL
+	if doc == nil {
+		if cache.NegativeTTL < 0 {
+			return
+		}
+		value, ttl = notFoundValue, cache.NegativeTTL
+	}
+
+	if err := cache.Redis.SetDataToCacheWithContext(context.Background(), key, value, ttl); err != nil {
+		logger.Warnf("mongodb cache: set %s failed: %v", key, err)
+		return
+	}
+	if err := cache.Redis.AddToSetWithContext(context.Background(), cache.Folder, key); err != nil {
+		logger.Warnf("mongodb cache: add %s to %s failed: %v", key, cache.Folder, err)
+		return
+	}
+	if err := cache.Redis.ExpireWithContext(context.Background(), cache.Folder, max(cache.TTL, cache.NegativeTTL)); err != nil {
+		logger.Warnf("mongodb cache: expire %s failed: %v", cache.Folder, err)
+	}
+}
+
+func (cache *collectionCache) invalidate(ids []primitive.ObjectID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	members := make([]any, len(ids))
+	for i, id := range ids {
+		keys[i] = cache.key(id)
+		members[i] = keys[i]
+	}
+
+	if err := cache.Redis.DeleteDataFromKeysWithContext(context.Background(), keys); err != nil {
+		logger.Warnf("mongodb cache: invalidating %v failed: %v", keys, err)
+		return
+	}
+	if err := cache.Redis.RemoveFromSetWithContext(context.Background(), cache.Folder, members...); err != nil {
+		logger.Warnf("mongodb cache: remove %v from %s failed: %v", keys, cache.Folder, err)
+	}
+}
+
+func (cache *collectionCache) invalidateAll() error {
+	return cache.Redis.RemoveASetWithContext(context.Background(), cache.Folder)
+}
+
+// invalidateEverything is invalidateAll logging its error, for the writes that may touch any document
+func (cache *collectionCache) invalidateEverything() {
+	if err := cache.invalidateAll(); err != nil {
+		logger.Warnf("mongodb cache: invalidating %s failed: %v", cache.Folder, err)
+	}
+}
+
+// invalidateFilter invalidates the ids selected by filter, or every cached document when they cannot be told
+func (cache *collectionCache) invalidateFilter(filter bson.M) {
+	ids, ok := filterIDs(filter)
+	if !ok {
+		cache.invalidateEverything()
+		return
+	}
+	cache.invalidate(ids)
+}
+
+// invalidateWritten invalidates the document a single-document write returned in doc, or every cached document
+// when a projection left its _id out. Documents with other _id types than ObjectID are never cached.
+func (cache *collectionCache) invalidateWritten(doc bson.Raw) {
+	value, err := doc.LookupErr("_id")
+	if err != nil {
+		cache.invalidateEverything()
+		return
+	}
+	if id, ok := value.ObjectIDOK(); ok {
+		cache.invalidate([]primitive.ObjectID{id})
+	}
+}
+
+// invalidateUpserted invalidates the cached miss of the document upserted by an update, or every cached document
+// when the update matched one instead, e.g. a document inserted concurrently
+func (cache *collectionCache) invalidateUpserted(result *mongo.UpdateResult) {
+	if result.MatchedCount > 0 {
+		cache.invalidateEverything()
+	} else if id, ok := result.UpsertedID.(primitive.ObjectID); ok {
+		cache.invalidate([]primitive.ObjectID{id})
+	}
+}
+
+// filterIDs returns the ids of a filter on _id with a value, $eq or $in
+func filterIDs(filter bson.M) ([]primitive.ObjectID, bool) {
+	switch value := filter["_id"].(type) {
+	case primitive.ObjectID:
+		return []primitive.ObjectID{value}, true
+	case bson.M:
+		if id, ok := value["$eq"].(primitive.ObjectID); ok && len(value) == 1 {
+			return []primitive.ObjectID{id}, true
+		}
+		if in, ok := value["$in"]; ok && len(value) == 1 {
+			return objectIDs(in)
+		}
+	}
+	return nil, false
+}
+
+// modelIDs returns the ids written by models, false when a model may write any document
+func modelIDs(models []mongo.WriteModel) ([]primitive.ObjectID, bool) {
+	ids := []primitive.ObjectID{}
+	for _, model := range models {
+		var filter any
+		switch model := model.(type) {
+		case *mongo.InsertOneModel:
+			ids = append(ids, recordIDs([]any{model.Document})...)
+			continue
+		case *mongo.UpdateOneModel:
+			filter = model.Filter
+		case *mongo.UpdateManyModel:
+			filter = model.Filter
+		case *mongo.ReplaceOneModel:
+			filter = model.Filter
+		case *mongo.DeleteOneModel:
+			filter = model.Filter
+		case *mongo.DeleteManyModel:
+			filter = model.Filter
+		default:
+			return nil, false
+		}
+
+		filterM, ok := filter.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		filtered, ok := filterIDs(filterM)
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, filtered...)
+	}
+	return ids, true
+}
+
+// recordIDs returns the ObjectID _id of the records that have one; the others get a new id on insert
+func recordIDs(records []any) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	for _, record := range records {
+		doc, err := toDocument(record)
+		if err != nil {
+			continue
+		}
+		if id, ok := lookupField(doc, "_id"); ok && !mongoWrites.IsZeroID(id) {
+			if id, ok := id.(primitive.ObjectID); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// idOnlyUpdate carries opts over to the findAndModify that updates like UpdateOne and returns only the _id
+func idOnlyUpdate(opts []*options.UpdateOptions) *options.FindOneAndUpdateOptions {
+	opt := options.MergeUpdateOptions(opts...)
+	return &options.FindOneAndUpdateOptions{
+		ArrayFilters:             opt.ArrayFilters,
+		BypassDocumentValidation: opt.BypassDocumentValidation,
+		BypassEmptyTsReplacement: opt.BypassEmptyTsReplacement,
+		Collation:                opt.Collation,
+		Comment:                  opt.Comment,
+		Hint:                     opt.Hint,
+		Let:                      opt.Let,
+		Upsert:                   opt.Upsert,
+		Projection:               bson.M{"_id": 1},
+	}
+}
+
+// idOnlyReplace carries opts over to the findAndModify that replaces like ReplaceOne and returns only the _id
+func idOnlyReplace(opts []*options.ReplaceOptions) *options.FindOneAndReplaceOptions {
+	opt := options.MergeReplaceOptions(opts...)
+	return &options.FindOneAndReplaceOptions{
+		BypassDocumentValidation: opt.BypassDocumentValidation,
+		BypassEmptyTsReplacement: opt.BypassEmptyTsReplacement,
+		Collation:                opt.Collation,
+		Comment:                  opt.Comment,
+		Hint:                     opt.Hint,
+		Let:                      opt.Let,
+		Upsert:                   opt.Upsert,
+		Projection:               bson.M{"_id": 1},
+	}
+}
+
+func objectIDs(values any) ([]primitive.ObjectID, bool) {
+	switch values := values.(type) {
+	case []primitive.ObjectID:
+		return values, true
+	case bson.A:
+		return objectIDs([]any(values))
+	case []any:
+		ids := make([]primitive.ObjectID, 0, len(values))
+		for _, value := range values {
+			id, ok := value.(primitive.ObjectID)
+			if !ok {
+				return nil, false
+			}
+			ids = append(ids, id)
+		}
+		return ids, true
+	}
+	return nil, false
+}
+
+// InvalidateAll removes every document cached by the collection
+func (collection CachedCollectionWrapper) InvalidateAll() error {
+	return collection.cache.invalidateAll()
+}
+
+// Invalidate removes the cached documents of ids, e.g. after a write made outside this wrapper
+func (collection CachedCollectionWrapper) Invalidate(ids ...primitive.ObjectID) {
+	collection.cache.invalidate(ids)
+}
+
+func (collection CachedCollectionWrapper) FindOneById(sessContext mongo.SessionContext, record any, id primitive.ObjectID) error {
+	if !collection.readsCache(sessContext) {
+		return collection.MongoCollectionWrapper.FindOneById(sessContext, record, id)
+	}
+
+	key := collection.cache.key(id)
+	cached, err := collection.cache.get([]string{key})
+	if err != nil {
+		logger.Warnf("mongodb cache: get %s failed: %v", key, err)
+	} else if cached[0] != nil {
+		if *cached[0] == notFoundValue {
+			return mongo.ErrNoDocuments
+		}
+		return bson.Unmarshal([]byte(*cached[0]), record)
+	}
+
+	// Concurrent misses on the same key share one query
+	result, err, _ := collection.cache.group.Do(key, func() (any, error) {
+		var doc bson.Raw
+		err := collection.MongoCollectionWrapper.FindOneById(nil, &doc, id)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			collection.cache.set(key, nil)
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+
+		collection.cache.set(key, doc)
+		return doc, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(result.(bson.Raw), record)
+}
+
+// FindManyByIds returns the found documents in the order of ids. opts bypass the cache, as they may change the documents.
+func (collection CachedCollectionWrapper) FindManyByIds(sessContext mongo.SessionContext, records any, ids []primitive.ObjectID, opts ...*options.FindOptions) error {
+	if !collection.readsCache(sessContext) || len(opts) > 0 || len(ids) == 0 {
+		return collection.MongoCollectionWrapper.FindManyByIds(sessContext, records, ids, opts...)
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = collection.cache.key(id)
+	}
+
+	docs := map[primitive.ObjectID]bson.Raw{}
+	missing := []primitive.ObjectID{}
+	cached, err := collection.cache.get(keys)
+	if err != nil {
+		logger.Warnf("mongodb cache: get %d keys failed: %v", len(keys), err)
+		missing = ids
+	} else {
+		for i, value := range cached {
+			if value == nil {
+				missing = append(missing, ids[i])
+			} else if *value != notFoundValue {
+				docs[ids[i]] = bson.Raw(*value)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		flightKey := make([]string, len(missing))
+		for i, id := range missing {
+			flightKey[i] = id.Hex()
+		}
+
+		result, err, _ := collection.cache.group.Do(strings.Join(flightKey, ","), func() (any, error) {
+			found := []bson.Raw{}
+			if err := collection.MongoCollectionWrapper.FindManyByIds(nil, &found, missing); err != nil {
+				return nil, err
+			}
+
+			foundByID := make(map[primitive.ObjectID]bson.Raw, len(found))
+			for _, doc := range found {
+				if id, ok := doc.Lookup("_id").ObjectIDOK(); ok {
+					foundByID[id] = doc
+				}
+			}
+			for _, id := range missing {
+				collection.cache.set(collection.cache.key(id), foundByID[id])
+			}
+			return foundByID, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for id, doc := range result.(map[primitive.ObjectID]bson.Raw) {
+			docs[id] = doc
+		}
+	}
+
+	ordered := make([]bson.Raw, 0, len(docs))
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		if doc, ok := docs[id]; ok && !seen[id] {
+			ordered = append(ordered, doc)
+			seen[id] = true
+		}
+	}
+
+	return decodeRawDocuments(ordered, records)
+}
+
+func (collection CachedCollectionWrapper) InsertOne(sessContext mongo.SessionContext, newRecord interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	result, err := collection.MongoCollectionWrapper.InsertOne(sessContext, newRecord, opts...)
+	if err == nil {
+		// Drops a cached miss for a caller-chosen id
+		if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidate([]primitive.ObjectID{id}) })
+		}
+	}
+	return result, err
+}
+
+func (collection CachedCollectionWrapper) InsertMany(sessContext mongo.SessionContext, newRecords []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	result, err := collection.MongoCollectionWrapper.InsertMany(sessContext, newRecords, opts...)
+	if result != nil {
+		if ids, ok := objectIDs(result.InsertedIDs); ok {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidate(ids) })
+		}
+	}
+	return result, err
+}
+
+// UpdateOne with a filter that is not on _id runs as a findAndModify returning the _id of the updated document.
+// Its result then counts the matched document as modified, as findAndModify does not tell whether it changed.
+func (collection CachedCollectionWrapper) UpdateOne(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if _, ok := filterIDs(filter); ok {
+		result, err := collection.MongoCollectionWrapper.UpdateOne(sessContext, filter, update, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return result, err
+	}
+
+	// An upsert first tries to update, as findAndModify does not tell an update from an insert
+	findOpts := idOnlyUpdate(opts)
+	upsert := findOpts.Upsert != nil && *findOpts.Upsert
+	findOpts.Upsert = nil
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndUpdate(sessContext, &doc, filter, update, findOpts)
+	if errors.Is(err, mongo.ErrNoDocuments) && upsert {
+		result, err := collection.MongoCollectionWrapper.UpdateOne(sessContext, filter, update, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateUpserted(result) })
+		}
+		return result, err
+	} else if errors.Is(err, mongo.ErrNoDocuments) {
+		return &mongo.UpdateResult{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	collection.afterWrite(sessContext, func() { collection.cache.invalidateWritten(doc) })
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+func (collection CachedCollectionWrapper) UpdateMany(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	result, err := collection.MongoCollectionWrapper.UpdateMany(sessContext, filter, update, opts...)
+	if err == nil {
+		collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+	}
+	return result, err
+}
+
+// ReplaceOne with a filter that is not on _id runs as a findAndModify returning the _id of the replaced document
+func (collection CachedCollectionWrapper) ReplaceOne(sessContext mongo.SessionContext, filter bson.M, updateRecord any, opts ...*options.ReplaceOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.ReplaceOne(sessContext, filter, updateRecord, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	// Returning the document after the write also gives the _id of an upserted one
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndReplace(sessContext, &doc, filter, updateRecord, idOnlyReplace(opts).SetReturnDocument(options.After))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	collection.afterWrite(sessContext, func() { collection.cache.invalidateWritten(doc) })
+	return nil
+}
+
+// FindOneAndUpdate with a filter that is not on _id invalidates the returned document
+func (collection CachedCollectionWrapper) FindOneAndUpdate(sessContext mongo.SessionContext, record any, filter bson.M, update bson.M, opts ...*options.FindOneAndUpdateOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.FindOneAndUpdate(sessContext, record, filter, update, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndUpdate(sessContext, &doc, filter, update, opts...)
+	upsert := options.MergeFindOneAndUpdateOptions(opts...).Upsert
+	return collection.afterFindOneAnd(sessContext, record, doc, err, upsert != nil && *upsert)
+}
+
+// FindOneAndReplace with a filter that is not on _id invalidates the returned document
+func (collection CachedCollectionWrapper) FindOneAndReplace(sessContext mongo.SessionContext, returnRecord any, filter bson.M, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.FindOneAndReplace(sessContext, returnRecord, filter, updateRecord, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndReplace(sessContext, &doc, filter, updateRecord, opts...)
+	upsert := options.MergeFindOneAndReplaceOptions(opts...).Upsert
+	return collection.afterFindOneAnd(sessContext, returnRecord, doc, err, upsert != nil && *upsert)
+}
+
+// afterFindOneAnd invalidates the document doc returned by a findAndModify and decodes it into record. No document
+// is returned when nothing matched, or when an upsert inserted one and the document before the write was asked for;
+// its new _id is then unknown, so that every cached document is invalidated.
+func (collection CachedCollectionWrapper) afterFindOneAnd(sessContext mongo.SessionContext, record any, doc bson.Raw, err error, upsert bool) error {
+	if errors.Is(err, mongo.ErrNoDocuments) && upsert {
+		collection.afterWrite(sessContext, collection.cache.invalidateEverything)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	collection.afterWrite(sessContext, func() { collection.cache.invalidateWritten(doc) })
+	return bson.Unmarshal(doc, record)
+}
+
+// UpdateOneVersioned with a filter that is not on _id runs as a findAndModify returning the _id of the updated
+// document, and then counts the matched document as modified
+func (collection CachedCollectionWrapper) UpdateOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if _, ok := filterIDs(filter); ok {
+		result, err := collection.MongoCollectionWrapper.UpdateOneVersioned(sessContext, filter, version, update, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return result, err
+	}
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndUpdateVersioned(sessContext, &doc, filter, version, update, idOnlyUpdate(opts))
+	if errors.Is(err, ErrVersionConflict) {
+		return &mongo.UpdateResult{}, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	collection.afterWrite(sessContext, func() { collection.cache.invalidateWritten(doc) })
+	return &mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil
+}
+
+// ReplaceOneVersioned with a filter that is not on _id runs as a findAndModify returning the _id of the replaced document
+func (collection CachedCollectionWrapper) ReplaceOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, updateRecord any, opts ...*options.ReplaceOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.ReplaceOneVersioned(sessContext, filter, version, updateRecord, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndReplaceVersioned(sessContext, &doc, filter, version, updateRecord, idOnlyReplace(opts))
+	if err != nil {
+		return err
+	}
+
+	collection.afterWrite(sessContext, func() { collection.cache.invalidateWritten(doc) })
+	return nil
+}
+
+// FindOneAndUpdateVersioned with a filter that is not on _id invalidates the returned document
+func (collection CachedCollectionWrapper) FindOneAndUpdateVersioned(sessContext mongo.SessionContext, record any, filter bson.M, version int64, update bson.M, opts ...*options.FindOneAndUpdateOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.FindOneAndUpdateVersioned(sessContext, record, filter, version, update, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	// Versioned writes reject upserts
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndUpdateVersioned(sessContext, &doc, filter, version, update, opts...)
+	return collection.afterFindOneAnd(sessContext, record, doc, err, false)
+}
+
+// FindOneAndReplaceVersioned with a filter that is not on _id invalidates the returned document
+func (collection CachedCollectionWrapper) FindOneAndReplaceVersioned(sessContext mongo.SessionContext, returnRecord any, filter bson.M, version int64, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
+	if _, ok := filterIDs(filter); ok {
+		err := collection.MongoCollectionWrapper.FindOneAndReplaceVersioned(sessContext, returnRecord, filter, version, updateRecord, opts...)
+		if err == nil {
+			collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+		}
+		return err
+	}
+
+	var doc bson.Raw
+	err := collection.MongoCollectionWrapper.FindOneAndReplaceVersioned(sessContext, &doc, filter, version, updateRecord, opts...)
+	return collection.afterFindOneAnd(sessContext, returnRecord, doc, err, false)
+}
+
+func (collection CachedCollectionWrapper) DeleteOneById(sessContext mongo.SessionContext, id primitive.ObjectID) error {
+	err := collection.MongoCollectionWrapper.DeleteOneById(sessContext, id)
+	if err == nil {
+		collection.afterWrite(sessContext, func() { collection.cache.invalidate([]primitive.ObjectID{id}) })
+	}
+	return err
+}
+
+func (collection CachedCollectionWrapper) DeleteManyByIds(sessContext mongo.SessionContext, ids []primitive.ObjectID) error {
+	err := collection.MongoCollectionWrapper.DeleteManyByIds(sessContext, ids)
+	if err == nil {
+		collection.afterWrite(sessContext, func() { collection.cache.invalidate(ids) })
+	}
+	return err
+}
+
+func (collection CachedCollectionWrapper) DeleteMany(sessContext mongo.SessionContext, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	result, err := collection.MongoCollectionWrapper.DeleteMany(sessContext, filter, opts...)
+	if err == nil {
+		collection.afterWrite(sessContext, func() { collection.cache.invalidateFilter(filter) })
+	}
+	return result, err
+}
+
+// BulkWrite invalidates the ids of the models when their filters are bson.M on _id, and every cached document
+// otherwise, as the models may touch any of them
+func (collection CachedCollectionWrapper) BulkWrite(sessContext mongo.SessionContext, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	result, err := collection.MongoCollectionWrapper.BulkWrite(sessContext, models, opts...)
+	if result == nil {
+		return result, err
+	}
+
+	if ids, ok := modelIDs(models); ok {
+		collection.afterWrite(sessContext, func() { collection.cache.invalidate(ids) })
+	} else {
+		collection.afterWrite(sessContext, collection.cache.invalidateEverything)
+	}
+	return result, err
+}
+
+// InsertManyChunked only adds documents, so it only invalidates the cached misses of the ids chosen by the records
+func (collection CachedCollectionWrapper) InsertManyChunked(sessContext mongo.SessionContext, records []interface{}, opts ...BulkOptions) (BulkReport, error) {
+	report, err := collection.MongoCollectionWrapper.InsertManyChunked(sessContext, records, opts...)
+	if report.Inserted > 0 && collection.cache.NegativeTTL >= 0 {
+		ids := recordIDs(records)
+		collection.afterWrite(sessContext, func() { collection.cache.invalidate(ids) })
+	}
+	return report, err
+}
+
+// UpsertManyBy invalidates the ids of the records when keyFields is _id, and every cached document otherwise,
+// as the documents matched by other key fields are not returned
+func (collection CachedCollectionWrapper) UpsertManyBy(sessContext mongo.SessionContext, keyFields []string, records []interface{}, opts ...BulkOptions) (BulkReport, error) {
+	report, err := collection.MongoCollectionWrapper.UpsertManyBy(sessContext, keyFields, records, opts...)
+	if report.Matched == 0 && report.Upserted == 0 {
+		return report, err
+	}
+
+	if len(keyFields) == 1 && keyFields[0] == "_id" {
+		ids := recordIDs(records)
+		collection.afterWrite(sessContext, func() { collection.cache.invalidate(ids) })
+	} else {
+		collection.afterWrite(sessContext, collection.cache.invalidateEverything)
+	}
+	return report, err
+}
@@ -0,0 +1,264 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	awsRedis "github.com/BeeTechHub/go-common/aws/redis"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChangeEvent is a change stream event with the full document decoded into T
+type ChangeEvent[T any] struct {
+	ResumeToken       bson.Raw            `bson:"_id"`
+	OperationType     string              `bson:"operationType"`
+	ClusterTime       primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey       bson.M              `bson:"documentKey"`
+	FullDocument      *T                  `bson:"fullDocument"`
+	UpdateDescription *UpdateDescription  `bson:"updateDescription"`
+	Namespace         struct {
+		Database   string `bson:"db"`
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+}
+
+type UpdateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// ErrResumeTokenLost is returned by Watch when the stream cannot resume from the saved token, e.g. because the
+// oplog rolled over it, unless WatchOptions.RestartOnTokenLost is set
+var ErrResumeTokenLost = errors.New("mongodb: change stream cannot resume from its token")
+
+// Server error codes and labels of change streams that fail again when reopened from the same token
+const (
+	invalidResumeTokenCode      = 260
+	changeStreamFatalErrorCode  = 280
+	changeStreamHistoryLostCode = 286
+	changeStreamFatalErrorLabel = "ChangeStreamFatalError"
+)
+
+func isNonResumable(err error) bool {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return false
+	}
+	return serverErr.HasErrorCode(changeStreamHistoryLostCode) ||
+		serverErr.HasErrorCode(invalidResumeTokenCode) ||
+		serverErr.HasErrorCode(changeStreamFatalErrorCode) ||
+		serverErr.HasErrorLabel(changeStreamFatalErrorLabel)
+}
+
+// streamError marks the stream errors that reopening from the same token cannot fix
+func streamError(err error) error {
+	if err != nil && isNonResumable(err) {
+		return fmt.Errorf("%w: %w", ErrResumeTokenLost, err)
+	}
+	return err
+}
+
+// ResumeTokenStore persists the resume token of a watcher so it can continue after a restart
+type ResumeTokenStore interface {
+	// Load returns nil when no token was saved for name
+	Load(ctx context.Context, name string) (bson.Raw, error)
+	// Save is called with an empty token to clear it
+	Save(ctx context.Context, name string, token bson.Raw) error
+}
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// Name identifies the watcher in the resume token store
+	Name string
+	// Pipeline filters or reshapes events, e.g. []bson.M{{"$match": bson.M{"operationType": "update"}}}
+	Pipeline []bson.M
+	// Store persists resume tokens; without it a restarted watcher only sees new events
+	Store ResumeTokenStore
+	// FullDocument defaults to options.UpdateLookup
+	FullDocument options.FullDocument
+	BatchSize    int32
+	MaxAwaitTime time.Duration
+	// ReconnectDelay is the first wait before reopening a failed stream, doubled up to MaxReconnectDelay (defaults 1s and 30s)
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// OnError is called with each error that makes the stream reconnect
+	OnError func(err error)
+	// RestartOnTokenLost clears the saved token and reopens the stream from now when it cannot resume from it,
+	// skipping the events in between; otherwise Watch returns ErrResumeTokenLost
+	RestartOnTokenLost bool
+}
+
+// Watch opens a change stream on the collection and calls handler for each event until ctx is done.
+// The resume token is saved only after handler returns nil; on a handler or stream error the stream
+// is reopened from the last handled event, so events are delivered at least once.
+func (collection MongoCollectionWrapper) Watch(ctx context.Context, opts WatchOptions, handler func(ctx context.Context, event ChangeEvent[bson.Raw]) error) error {
+	return WatchAs(ctx, collection, opts, handler)
+}
+
+// WatchAs is Watch with the full document decoded into T
+func WatchAs[T any](ctx context.Context, collection MongoCollectionWrapper, opts WatchOptions, handler func(ctx context.Context, event ChangeEvent[T]) error) error {
+	if opts.FullDocument == "" {
+		opts.FullDocument = options.UpdateLookup
+	}
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = time.Second
+	}
+	if opts.MaxReconnectDelay <= 0 {
+		opts.MaxReconnectDelay = 30 * time.Second
+	}
+
+	var token bson.Raw
+	if opts.Store != nil {
+		var err error
+		if token, err = opts.Store.Load(ctx, opts.Name); err != nil {
+			return err
+		}
+	}
+
+	delay := opts.ReconnectDelay
+	for {
+		handled := false
+		err := watchOnce(ctx, collection, opts, &token, &handled, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if opts.OnError != nil && err != nil {
+			opts.OnError(err)
+		}
+
+		if errors.Is(err, ErrResumeTokenLost) {
+			if !opts.RestartOnTokenLost {
+				return err
+			}
+			token = nil
+			if opts.Store != nil {
+				if err := opts.Store.Save(ctx, opts.Name, nil); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+
+		if handled {
+			delay = opts.ReconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, opts.MaxReconnectDelay)
+	}
+}
+
+// watchOnce consumes one change stream until it fails, updating token after each handled event
+func watchOnce[T any](ctx context.Context, collection MongoCollectionWrapper, opts WatchOptions, token *bson.Raw, handled *bool, handler func(ctx context.Context, event ChangeEvent[T]) error) error {
+	streamOpts := options.ChangeStream().SetFullDocument(opts.FullDocument)
+	if opts.BatchSize > 0 {
+		streamOpts.SetBatchSize(opts.BatchSize)
+	}
+	if opts.MaxAwaitTime > 0 {
+		streamOpts.SetMaxAwaitTime(opts.MaxAwaitTime)
+	}
+	if len(*token) > 0 {
+		// startAfter, unlike resumeAfter, also resumes after an invalidate event
+		streamOpts.SetStartAfter(*token)
+	}
+
+	pipeline := opts.Pipeline
+	if pipeline == nil {
+		pipeline = []bson.M{}
+	}
+
+	stream, err := collection.Collection.Watch(ctx, pipeline, streamOpts)
+	if err != nil {
+		return streamError(err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event ChangeEvent[T]
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+
+		*token = append(bson.Raw(nil), stream.ResumeToken()...)
+		*handled = true
+		if opts.Store != nil {
+			if err := opts.Store.Save(ctx, opts.Name, *token); err != nil {
+				return err
+			}
+		}
+	}
+
+	return streamError(stream.Err())
+}
+
+// MongoResumeTokenStore keeps resume tokens in a collection, one document per watcher name
+type MongoResumeTokenStore struct {
+	Collection MongoCollectionWrapper
+}
+
+type resumeTokenRecord struct {
+	Name      string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+func (store MongoResumeTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	var record resumeTokenRecord
+	err := store.Collection.Collection.FindOne(ctx, bson.M{"_id": name}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return record.Token, nil
+}
+
+func (store MongoResumeTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	if len(token) == 0 {
+		_, err := store.Collection.Collection.DeleteOne(ctx, bson.M{"_id": name})
+		return err
+	}
+
+	record := resumeTokenRecord{name, token, time.Now()}
+	_, err := store.Collection.Collection.ReplaceOne(ctx, bson.M{"_id": name}, record, options.Replace().SetUpsert(true))
+	return err
+}
+
+// RedisResumeTokenStore keeps resume tokens in redis under KeyPrefix + name
+type RedisResumeTokenStore struct {
+	Redis     *awsRedis.RedisClientWrapper
+	KeyPrefix string
+	// Expire is the token TTL, 0 keeps it forever
+	Expire time.Duration
+}
+
+func (store RedisResumeTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	value, err := store.Redis.GetValueFromKeyWithContext(ctx, store.KeyPrefix+name)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	return bson.Raw(*value), nil
+}
+
+func (store RedisResumeTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	if len(token) == 0 {
+		return store.Redis.DeleteDataFromKeyWithContext(ctx, store.KeyPrefix+name)
+	}
+	return store.Redis.SetDataToCacheWithContext(ctx, store.KeyPrefix+name, string(token), store.Expire)
+}
@@ -0,0 +1,50 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoResumeTokenStoreSave(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	token, err := bson.Marshal(bson.M{"_data": "0001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		token       bson.Raw
+		wantCommand string
+	}{
+		{"token is upserted", token, "update"},
+		{"nil token is deleted", nil, "delete"},
+		{"empty token is deleted", bson.Raw{}, "delete"},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			store := MongoResumeTokenStore{Collection: MongoCollectionWrapper{Collection: mt.Coll}}
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+			if err := store.Save(context.Background(), "watcher", tt.token); err != nil {
+				mt.Fatalf("Save returned %v", err)
+			}
+			if event := mt.GetStartedEvent(); event == nil || event.CommandName != tt.wantCommand {
+				mt.Fatalf("Save sent %v, want %s", event, tt.wantCommand)
+			}
+
+			// The server finds no document once the token is deleted
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.tokens", mtest.FirstBatch))
+			loaded, err := store.Load(context.Background(), "watcher")
+			if err != nil {
+				mt.Fatalf("Load returned %v", err)
+			}
+			if loaded != nil {
+				mt.Errorf("Load = %v, want nil", loaded)
+			}
+		})
+	}
+}
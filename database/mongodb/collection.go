@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is the set of operations of MongoCollectionWrapper, so that code using a collection
+// can be tested against an in-memory implementation such as the one of package mongoFake.
+// The configuration methods returning a new MongoCollectionWrapper (WithAudit, WithTimeout...) are not part of it.
+type Collection interface {
+	Count(sessContext mongo.SessionContext, filter bson.M, opts ...*options.CountOptions) (int64, error)
+	FindOne(sessContext mongo.SessionContext, record any, filter bson.M, opts ...*options.FindOneOptions) error
+	FindMany(sessContext mongo.SessionContext, records any, filter bson.M, opts ...*options.FindOptions) error
+	FindManyWithAggregation(sessContext mongo.SessionContext, records any, pipeline []bson.M, opts ...*options.AggregateOptions) error
+	FindOneById(sessContext mongo.SessionContext, record any, id primitive.ObjectID) error
+	FindManyByIds(sessContext mongo.SessionContext, records any, ids []primitive.ObjectID, opts ...*options.FindOptions) error
+	FindPaginated(sessContext mongo.SessionContext, records any, page int64, size int64, filter bson.M, sortParams bson.D, pipe ...bson.M) (int64, error)
+	AggregatePaginated(sessContext mongo.SessionContext, records any, page int64, size int64, pipeline []bson.M, sortParams bson.D, opts ...*options.AggregateOptions) (int64, error)
+	FindWithCursor(sessContext mongo.SessionContext, records any, cursor string, size int64, filter bson.M, sortParams bson.D) (CursorPage, error)
+	FindEach(sessContext mongo.SessionContext, filter bson.M, handle func(doc bson.Raw) error, opts ...*options.FindOptions) error
+	AggregateEach(sessContext mongo.SessionContext, pipeline []bson.M, handle func(doc bson.Raw) error, opts ...*options.AggregateOptions) error
+
+	InsertOne(sessContext mongo.SessionContext, newRecord interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	InsertMany(sessContext mongo.SessionContext, newRecords []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
+	UpdateOne(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	ReplaceOne(sessContext mongo.SessionContext, filter bson.M, updateRecord any, opts ...*options.ReplaceOptions) error
+	FindOneAndUpdate(sessContext mongo.SessionContext, record any, filter bson.M, update bson.M, opts ...*options.FindOneAndUpdateOptions) error
+	FindOneAndReplace(sessContext mongo.SessionContext, returnRecord any, filter bson.M, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error
+	DeleteMany(sessContext mongo.SessionContext, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteOneById(sessContext mongo.SessionContext, id primitive.ObjectID) error
+	DeleteManyByIds(sessContext mongo.SessionContext, ids []primitive.ObjectID) error
+	BulkWrite(sessContext mongo.SessionContext, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	InsertManyChunked(sessContext mongo.SessionContext, records []interface{}, opts ...BulkOptions) (BulkReport, error)
+	UpsertManyBy(sessContext mongo.SessionContext, keyFields []string, records []interface{}, opts ...BulkOptions) (BulkReport, error)
+
+	UpdateOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	ReplaceOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, updateRecord any, opts ...*options.ReplaceOptions) error
+	FindOneAndUpdateVersioned(sessContext mongo.SessionContext, record any, filter bson.M, version int64, update bson.M, opts ...*options.FindOneAndUpdateOptions) error
+	FindOneAndReplaceVersioned(sessContext mongo.SessionContext, returnRecord any, filter bson.M, version int64, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error
+
+	EnsureIndexes(sessContext mongo.SessionContext, models []mongo.IndexModel, opts ...EnsureIndexesOptions) (IndexReport, error)
+	Watch(ctx context.Context, opts WatchOptions, handler func(ctx context.Context, event ChangeEvent[bson.Raw]) error) error
+}
+
+var _ Collection = MongoCollectionWrapper{}
+var _ Collection = CachedCollectionWrapper{}
@@ -0,0 +1,214 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BeeTechHub/go-common/logger"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// MongoMetrics receives command and connection pool events, e.g. to feed Prometheus
+type MongoMetrics interface {
+	ObserveCommand(commandName string, duration time.Duration, err error)
+	ObservePoolEvent(eventType string, address string)
+}
+
+// ConnectOptions configures Connect. Zero values keep the driver defaults or the values set in Uri.
+type ConnectOptions struct {
+	Uri string
+	// Timeout bounds connecting and is the default timeout of every operation
+	Timeout time.Duration
+	AppName string
+
+	MinPoolSize     uint64
+	MaxPoolSize     uint64
+	MaxConnIdleTime time.Duration
+
+	ReadPreference *readpref.ReadPref
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	RetryWrites    *bool
+	RetryReads     *bool
+
+	// TLSCAFile is a PEM file of the CA certificates used to verify the server, e.g. the AWS DocumentDB bundle
+	TLSCAFile string
+	// TLSCertificateKeyFile is a PEM file holding the client certificate and its private key
+	TLSCertificateKeyFile string
+	TLSInsecure           bool
+
+	// LogCommands logs failed commands, and commands slower than SlowCommandThreshold, with the logger package
+	LogCommands          bool
+	SlowCommandThreshold time.Duration
+	Metrics              MongoMetrics
+}
+
+func (opts ConnectOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: opts.TLSInsecure}
+
+	if opts.TLSCAFile != "" {
+		pem, err := os.ReadFile(opts.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mongodb: no certificate found in %s", opts.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.TLSCertificateKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(opts.TLSCertificateKeyFile, opts.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+func (opts ConnectOptions) commandMonitor() *event.CommandMonitor {
+	observe := func(commandName string, duration time.Duration, err error) {
+		if opts.Metrics != nil {
+			opts.Metrics.ObserveCommand(commandName, duration, err)
+		}
+		if !opts.LogCommands {
+			return
+		}
+		if err != nil {
+			logger.Warnf("mongodb command %s failed after %s: %v", commandName, duration, err)
+		} else if opts.SlowCommandThreshold > 0 && duration >= opts.SlowCommandThreshold {
+			logger.Warnf("mongodb command %s is slow: %s", commandName, duration)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			observe(evt.CommandName, evt.Duration, nil)
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			observe(evt.CommandName, evt.Duration, errors.New(evt.Failure))
+		},
+	}
+}
+
+func (opts ConnectOptions) poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			if opts.Metrics != nil {
+				opts.Metrics.ObservePoolEvent(evt.Type, evt.Address)
+			}
+			if opts.LogCommands && evt.Type == event.PoolCleared {
+				logger.Warnf("mongodb connection pool for %s cleared: %v", evt.Address, evt.Error)
+			}
+		},
+	}
+}
+
+func (opts ConnectOptions) clientOptions() (*options.ClientOptions, error) {
+	clientOpts := options.Client().ApplyURI(opts.Uri)
+
+	if opts.Timeout > 0 {
+		clientOpts.SetTimeout(opts.Timeout).SetConnectTimeout(opts.Timeout)
+	}
+	if opts.AppName != "" {
+		clientOpts.SetAppName(opts.AppName)
+	}
+	if opts.MinPoolSize > 0 {
+		clientOpts.SetMinPoolSize(opts.MinPoolSize)
+	}
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.MaxConnIdleTime > 0 {
+		clientOpts.SetMaxConnIdleTime(opts.MaxConnIdleTime)
+	}
+	if opts.ReadPreference != nil {
+		clientOpts.SetReadPreference(opts.ReadPreference)
+	}
+	if opts.ReadConcern != nil {
+		clientOpts.SetReadConcern(opts.ReadConcern)
+	}
+	if opts.WriteConcern != nil {
+		clientOpts.SetWriteConcern(opts.WriteConcern)
+	}
+	if opts.RetryWrites != nil {
+		clientOpts.SetRetryWrites(*opts.RetryWrites)
+	}
+	if opts.RetryReads != nil {
+		clientOpts.SetRetryReads(*opts.RetryReads)
+	}
+
+	if opts.TLSCAFile != "" || opts.TLSCertificateKeyFile != "" || opts.TLSInsecure {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetTLSConfig(tlsConfig)
+	}
+
+	if opts.LogCommands || opts.Metrics != nil {
+		clientOpts.SetMonitor(opts.commandMonitor()).SetPoolMonitor(opts.poolMonitor())
+	}
+
+	return clientOpts, clientOpts.Validate()
+}
+
+// Connect connects to MongoDB and pings the primary
+func Connect(opts ConnectOptions) (MongoClientWrapper, error) {
+	clientOpts, err := opts.clientOptions()
+	if err != nil {
+		return MongoClientWrapper{}, err
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	defer cancel()
+
+	fmt.Println("Try to connect to MongoDB")
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		fmt.Println(err)
+		return MongoClientWrapper{client}, err
+	}
+
+	//ping the database
+	err = client.Ping(ctx, readpref.Primary())
+	if err != nil {
+		fmt.Println(err)
+		_ = client.Disconnect(context.Background())
+		return MongoClientWrapper{client}, err
+	}
+	fmt.Println("Connected to MongoDB")
+	return MongoClientWrapper{client}, nil
+}
+
+// Disconnect closes the connections of the client, waiting for in-use ones until ctx is done
+func (client MongoClientWrapper) Disconnect(ctx context.Context) error {
+	return client.Client.Disconnect(ctx)
+}
+
+// HealthCheck pings the primary, or the given read preference, e.g. for a readiness probe
+func (client MongoClientWrapper) HealthCheck(ctx context.Context, rp ...*readpref.ReadPref) error {
+	pref := readpref.Primary()
+	if len(rp) > 0 && rp[0] != nil {
+		pref = rp[0]
+	}
+
+	return client.Client.Ping(ctx, pref)
+}
@@ -0,0 +1,318 @@
+package mongodb
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidCursor = errors.New("mongodb: invalid pagination cursor")
+
+// CursorPage holds the continuation tokens of a page returned by FindWithCursor.
+// A token is empty when there is no page in that direction.
+type CursorPage struct {
+	NextCursor string
+	PrevCursor string
+}
+
+type cursorDirection string
+
+const (
+	cursorAfter  cursorDirection = "a"
+	cursorBefore cursorDirection = "b"
+)
+
+// cursorToken is the decoded form of a continuation token
+type cursorToken struct {
+	Direction cursorDirection `bson:"d"`
+	// Inclusive also matches the document at Values, used to page back from an empty page
+	Inclusive bool     `bson:"i,omitempty"`
+	Fields    []string `bson:"f"`
+	// Values are the sort values of the document, nil when the field is null or missing
+	Values bson.A `bson:"v"`
+}
+
+func encodeCursor(direction cursorDirection, sortParams bson.D, doc bson.Raw) (string, error) {
+	token := cursorToken{Direction: direction}
+	for _, sortElement := range sortParams {
+		token.Fields = append(token.Fields, sortElement.Key)
+		value, err := doc.LookupErr(strings.Split(sortElement.Key, ".")...)
+		if err != nil {
+			token.Values = append(token.Values, nil)
+		} else {
+			token.Values = append(token.Values, value)
+		}
+	}
+
+	return encodeToken(token)
+}
+
+func encodeToken(token cursorToken) (string, error) {
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string, sortParams bson.D) (cursorToken, error) {
+	var token cursorToken
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return token, ErrInvalidCursor
+	}
+
+	if err := bson.Unmarshal(data, &token); err != nil {
+		return token, ErrInvalidCursor
+	}
+
+	if (token.Direction != cursorAfter && token.Direction != cursorBefore) ||
+		len(token.Fields) != len(sortParams) || len(token.Values) != len(sortParams) {
+		return token, ErrInvalidCursor
+	}
+
+	for i, sortElement := range sortParams {
+		if token.Fields[i] != sortElement.Key {
+			return token, ErrInvalidCursor
+		}
+	}
+
+	return token, nil
+}
+
+// normalizeSortParams defaults to {_id: -1} and makes sure _id is the last sort key, so the order is total
+func normalizeSortParams(sortParams bson.D) bson.D {
+	if len(sortParams) == 0 {
+		return bson.D{{Key: "_id", Value: -1}}
+	}
+
+	for _, sortElement := range sortParams {
+		if sortElement.Key == "_id" {
+			return sortParams
+		}
+	}
+
+	return append(slices.Clone(sortParams), bson.E{Key: "_id", Value: -1})
+}
+
+func isDescending(value interface{}) bool {
+	switch v := value.(type) {
+	case int:
+		return v < 0
+	case int32:
+		return v < 0
+	case int64:
+		return v < 0
+	case float64:
+		return v < 0
+	default:
+		return false
+	}
+}
+
+// keysetCondition adds to condition the match of key after value in the sort order, or before it when less is set.
+// Null and missing values sort first, and comparison operators never match them, so they get their own branches.
+// It returns false when no value can match.
+func keysetCondition(condition bson.M, key string, value interface{}, less bool, inclusive bool) bool {
+	switch {
+	case value == nil && !less:
+		if !inclusive {
+			condition[key] = bson.M{"$ne": nil}
+		}
+	case value == nil && less:
+		if !inclusive {
+			return false
+		}
+		condition[key] = nil
+	case !less:
+		operator := "$gt"
+		if inclusive {
+			operator = "$gte"
+		}
+		condition[key] = bson.M{operator: value}
+	default:
+		operator := "$lt"
+		if inclusive {
+			operator = "$lte"
+		}
+		condition["$or"] = bson.A{bson.M{key: bson.M{operator: value}}, bson.M{key: nil}}
+	}
+	return true
+}
+
+// keysetFilter matches documents strictly after (or before, when backward) the sort values of token:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... The last key also matches v when inclusive is set.
+func keysetFilter(sortParams bson.D, values bson.A, backward bool, inclusive bool) bson.M {
+	or := bson.A{}
+	for i, sortElement := range sortParams {
+		condition := bson.M{}
+		for j := 0; j < i; j++ {
+			condition[sortParams[j].Key] = values[j]
+		}
+
+		less := isDescending(sortElement.Value) != backward
+		if keysetCondition(condition, sortElement.Key, values[i], less, inclusive && i == len(sortParams)-1) {
+			or = append(or, condition)
+		}
+	}
+
+	if len(or) == 0 {
+		// Every document has an _id
+		return bson.M{"_id": bson.M{"$exists": false}}
+	}
+	return bson.M{"$or": or}
+}
+
+func reverseSortParams(sortParams bson.D) bson.D {
+	reversed := make(bson.D, len(sortParams))
+	for i, sortElement := range sortParams {
+		if isDescending(sortElement.Value) {
+			reversed[i] = bson.E{Key: sortElement.Key, Value: 1}
+		} else {
+			reversed[i] = bson.E{Key: sortElement.Key, Value: -1}
+		}
+	}
+	return reversed
+}
+
+// decodeRawDocuments decodes docs into records, which must be a pointer to a slice
+func decodeRawDocuments(docs []bson.Raw, records any) error {
+	value := reflect.ValueOf(records)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("mongodb: records must be a pointer to a slice, got %T", records)
+	}
+
+	slice := value.Elem()
+	elemType := slice.Type().Elem()
+	result := reflect.MakeSlice(slice.Type(), 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(elemType)
+		if err := bson.Unmarshal(doc, elem.Interface()); err != nil {
+			return err
+		}
+		result = reflect.Append(result, elem.Elem())
+	}
+
+	slice.Set(result)
+	return nil
+}
+
+// FindWithCursor finds one page of records using keyset pagination instead of $skip, without counting documents.
+// cursor is empty for the first page, or a token from a previous CursorPage to page forward or backward.
+// sortParams is typically the output of utils.GetSortParamsForMongoDb; _id is appended as a tie-breaker if missing.
+func (collection MongoCollectionWrapper) FindWithCursor(sessContext mongo.SessionContext, records any, cursor string, size int64, filter bson.M, sortParams bson.D) (CursorPage, error) {
+	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
+	defer cancel()
+
+	find := func(query bson.M, sort bson.D, limit int64) ([]bson.Raw, error) {
+		mongoCursor, err := collection.Collection.Find(ctx, query, options.Find().SetSort(sort).SetLimit(limit))
+		if err != nil {
+			return nil, err
+		}
+		defer mongoCursor.Close(ctx)
+
+		docs := []bson.Raw{}
+		return docs, mongoCursor.All(ctx, &docs)
+	}
+
+	return PaginateWithCursor(find, records, cursor, size, collection.scopeFilter(filter), sortParams)
+}
+
+// CursorFinder returns up to limit documents matching filter in sort order
+type CursorFinder func(filter bson.M, sort bson.D, limit int64) ([]bson.Raw, error)
+
+// PaginateWithCursor implements FindWithCursor on top of find, for Collection implementations other than MongoCollectionWrapper
+func PaginateWithCursor(find CursorFinder, records any, cursor string, size int64, filter bson.M, sortParams bson.D) (CursorPage, error) {
+	page := CursorPage{}
+	if size < 1 {
+		return page, errors.New("mongodb: page size must be positive")
+	}
+	sortParams = normalizeSortParams(sortParams)
+
+	backward := false
+	query := filter
+	var token cursorToken
+	if cursor != "" {
+		var err error
+		if token, err = decodeCursor(cursor, sortParams); err != nil {
+			return page, err
+		}
+
+		backward = token.Direction == cursorBefore
+		keyset := keysetFilter(sortParams, token.Values, backward, token.Inclusive)
+		if len(filter) > 0 {
+			query = bson.M{"$and": bson.A{filter, keyset}}
+		} else {
+			query = keyset
+		}
+	}
+	if query == nil {
+		query = bson.M{}
+	}
+
+	querySort := sortParams
+	if backward {
+		querySort = reverseSortParams(sortParams)
+	}
+
+	// One extra record tells whether there is a page after this one
+	docs, err := find(query, querySort, size+1)
+	if err != nil {
+		return page, err
+	}
+
+	hasMore := int64(len(docs)) > size
+	if hasMore {
+		docs = docs[:size]
+	}
+	if backward {
+		slices.Reverse(docs)
+	}
+
+	if len(docs) == 0 && cursor != "" {
+		// Nothing beyond the cursor: point back to the documents it came from, the one it was taken at included
+		back := cursorToken{Direction: cursorBefore, Inclusive: true, Fields: token.Fields, Values: token.Values}
+		if backward {
+			back.Direction = cursorAfter
+		}
+
+		encoded, err := encodeToken(back)
+		if err != nil {
+			return page, err
+		}
+		if backward {
+			page.NextCursor = encoded
+		} else {
+			page.PrevCursor = encoded
+		}
+	}
+
+	if len(docs) > 0 {
+		// Forward: more means a next page, and a cursor means we came from a previous one; backward is the mirror
+		hasNext, hasPrev := hasMore, cursor != ""
+		if backward {
+			hasNext, hasPrev = true, hasMore
+		}
+
+		if hasNext {
+			if page.NextCursor, err = encodeCursor(cursorAfter, sortParams, docs[len(docs)-1]); err != nil {
+				return page, err
+			}
+		}
+		if hasPrev {
+			if page.PrevCursor, err = encodeCursor(cursorBefore, sortParams, docs[0]); err != nil {
+				return page, err
+			}
+		}
+	}
+
+	return page, decodeRawDocuments(docs, records)
+}
@@ -0,0 +1,290 @@
+package mongoFake
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// sortDocuments sorts docs in place, comparing arrays by their smallest (ascending) or largest (descending) element
+func sortDocuments(docs []bson.D, sort bson.D) {
+	if len(sort) == 0 {
+		return
+	}
+
+	slices.SortStableFunc(docs, func(a bson.D, b bson.D) int {
+		return compareDocuments(a, b, sort)
+	})
+}
+
+func compareDocuments(a bson.D, b bson.D, sort bson.D) int {
+	for _, key := range sort {
+		descending := false
+		if n, ok := toInt64(key.Value); ok {
+			descending = n < 0
+		} else if f, ok := key.Value.(float64); ok {
+			descending = f < 0
+		}
+
+		c := compareValues(sortKey(a, key.Key, descending), sortKey(b, key.Key, descending))
+		if descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func sortKey(doc bson.D, path string, descending bool) any {
+	values := resolve(doc, strings.Split(path, "."))
+	var key any
+	found := false
+	for _, value := range values {
+		elements := []any{value}
+		if array, ok := value.(bson.A); ok && len(array) > 0 {
+			elements = array
+		}
+		for _, elem := range elements {
+			if !found || (compareValues(elem, key) < 0) != descending {
+				key, found = elem, true
+			}
+		}
+	}
+	return key
+}
+
+func skipAndLimit(docs []bson.D, skip int64, limit int64) []bson.D {
+	if skip > 0 {
+		if skip >= int64(len(docs)) {
+			return []bson.D{}
+		}
+		docs = docs[skip:]
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+// project applies an inclusion or exclusion projection; computed fields are not supported
+func project(doc bson.D, projection bson.D) (bson.D, error) {
+	if len(projection) == 0 {
+		return doc, nil
+	}
+
+	include, excludeID := false, false
+	for _, field := range projection {
+		flag, ok := projectionFlag(field.Value)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: computed projection of %q is not supported", field.Key)
+		}
+		if field.Key == "_id" {
+			excludeID = !flag
+		} else if flag {
+			include = true
+		}
+	}
+
+	result := bson.D{}
+	if include {
+		if id, ok := lookupField(doc, "_id"); ok && !excludeID {
+			result = append(result, bson.E{Key: "_id", Value: id})
+		}
+		for _, field := range projection {
+			if flag, _ := projectionFlag(field.Value); !flag || field.Key == "_id" {
+				continue
+			}
+			if value, ok := getPath(doc, strings.Split(field.Key, ".")); ok {
+				var err error
+				if result, err = setPath(result, strings.Split(field.Key, "."), value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return result, nil
+	}
+
+	result = cloneDocument(doc)
+	for _, field := range projection {
+		result = unsetPath(result, strings.Split(field.Key, "."))
+	}
+	return result, nil
+}
+
+func projectionFlag(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case int32, int64, float64:
+		return toFloat(v) != 0, true
+	}
+	return false, false
+}
+
+// aggregate runs the supported stages of pipeline on docs
+func aggregate(docs []bson.D, pipeline bson.A) ([]bson.D, error) {
+	for _, item := range pipeline {
+		stage, ok := item.(bson.D)
+		if !ok || len(stage) != 1 {
+			return nil, fmt.Errorf("mongoFake: a pipeline stage must be a document with one field")
+		}
+
+		var err error
+		if docs, err = runStage(docs, stage[0]); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func runStage(docs []bson.D, stage bson.E) ([]bson.D, error) {
+	switch stage.Key {
+	case "$match":
+		filter, ok := stage.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: $match needs a document")
+		}
+		matched := []bson.D{}
+		for _, doc := range docs {
+			ok, err := matches(doc, filter)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched = append(matched, doc)
+			}
+		}
+		return matched, nil
+	case "$sort":
+		sort, ok := stage.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: $sort needs a document")
+		}
+		sorted := slices.Clone(docs)
+		sortDocuments(sorted, sort)
+		return sorted, nil
+	case "$skip", "$limit":
+		n, ok := toInt64(stage.Value)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: %s needs an integer", stage.Key)
+		}
+		if stage.Key == "$skip" {
+			return skipAndLimit(docs, n, 0), nil
+		}
+		return skipAndLimit(docs, 0, n), nil
+	case "$project", "$unset":
+		projection, ok := stage.Value.(bson.D)
+		if stage.Key == "$unset" {
+			projection, ok = unsetProjection(stage.Value)
+		}
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: invalid %s", stage.Key)
+		}
+		projected := make([]bson.D, 0, len(docs))
+		for _, doc := range docs {
+			doc, err := project(doc, projection)
+			if err != nil {
+				return nil, err
+			}
+			projected = append(projected, doc)
+		}
+		return projected, nil
+	case "$count":
+		field, ok := stage.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: $count needs a field name")
+		}
+		if len(docs) == 0 {
+			return []bson.D{}, nil
+		}
+		return []bson.D{{{Key: field, Value: int32(len(docs))}}}, nil
+	case "$unwind":
+		return unwind(docs, stage.Value)
+	case "$facet":
+		facets, ok := stage.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: $facet needs a document")
+		}
+		result := bson.D{}
+		for _, facet := range facets {
+			pipeline, ok := facet.Value.(bson.A)
+			if !ok {
+				return nil, fmt.Errorf("mongoFake: facet %q needs a pipeline", facet.Key)
+			}
+			output, err := aggregate(docs, pipeline)
+			if err != nil {
+				return nil, err
+			}
+			array := make(bson.A, len(output))
+			for i, doc := range output {
+				array[i] = doc
+			}
+			result = append(result, bson.E{Key: facet.Key, Value: array})
+		}
+		return []bson.D{result}, nil
+	}
+
+	return nil, fmt.Errorf("mongoFake: unsupported aggregation stage %s", stage.Key)
+}
+
+func unsetProjection(value any) (bson.D, bool) {
+	fields := bson.A{value}
+	if array, ok := value.(bson.A); ok {
+		fields = array
+	}
+
+	projection := bson.D{}
+	for _, field := range fields {
+		name, ok := field.(string)
+		if !ok {
+			return nil, false
+		}
+		projection = append(projection, bson.E{Key: name, Value: int32(0)})
+	}
+	return projection, true
+}
+
+func unwind(docs []bson.D, spec any) ([]bson.D, error) {
+	path, preserve := "", false
+	switch v := spec.(type) {
+	case string:
+		path = v
+	case bson.D:
+		value, _ := lookupField(v, "path")
+		path, _ = value.(string)
+		flag, _ := lookupField(v, "preserveNullAndEmptyArrays")
+		preserve, _ = flag.(bool)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("mongoFake: $unwind path must start with $")
+	}
+	parts := strings.Split(path[1:], ".")
+
+	unwound := []bson.D{}
+	for _, doc := range docs {
+		value, exists := getPath(doc, parts)
+		array, isArray := value.(bson.A)
+		switch {
+		case isArray && len(array) > 0:
+			for _, elem := range array {
+				clone, err := setPath(cloneDocument(doc), parts, cloneValue(elem))
+				if err != nil {
+					return nil, err
+				}
+				unwound = append(unwound, clone)
+			}
+		case exists && value != nil && !isArray:
+			unwound = append(unwound, doc)
+		case preserve:
+			if isArray {
+				doc = unsetPath(cloneDocument(doc), parts)
+			}
+			unwound = append(unwound, doc)
+		}
+	}
+	return unwound, nil
+}
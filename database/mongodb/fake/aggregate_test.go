@@ -0,0 +1,49 @@
+package mongoFake
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSortDocuments(t *testing.T) {
+	docs := []bson.D{
+		{{Key: "_id", Value: "a"}, {Key: "n", Value: int32(2)}, {Key: "s", Value: "x"}},
+		{{Key: "_id", Value: "b"}, {Key: "n", Value: 1.5}, {Key: "s", Value: "y"}},
+		{{Key: "_id", Value: "c"}, {Key: "s", Value: "x"}},
+		{{Key: "_id", Value: "d"}, {Key: "n", Value: nil}, {Key: "s", Value: "y"}},
+		{{Key: "_id", Value: "e"}, {Key: "n", Value: "text"}, {Key: "s", Value: "x"}},
+		{{Key: "_id", Value: "f"}, {Key: "n", Value: bson.A{int32(5), int32(0)}}, {Key: "s", Value: "y"}},
+	}
+
+	tests := []struct {
+		name string
+		sort bson.D
+		want []string
+	}{
+		// Missing and null sort first, then numbers, then strings; arrays sort by their smallest element
+		{"ascending", bson.D{{Key: "n", Value: int32(1)}}, []string{"c", "d", "f", "b", "a", "e"}},
+		// Arrays sort by their largest element
+		{"descending", bson.D{{Key: "n", Value: int32(-1)}}, []string{"e", "f", "a", "b", "c", "d"}},
+		{"go int direction", bson.D{{Key: "n", Value: -1}}, []string{"e", "f", "a", "b", "c", "d"}},
+		{"float direction", bson.D{{Key: "n", Value: -1.0}}, []string{"e", "f", "a", "b", "c", "d"}},
+		{"compound", bson.D{{Key: "s", Value: int32(1)}, {Key: "_id", Value: int32(-1)}}, []string{"e", "c", "a", "f", "d", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := slices.Clone(docs)
+			sortDocuments(sorted, tt.sort)
+
+			got := make([]string, len(sorted))
+			for i, doc := range sorted {
+				id, _ := lookupField(doc, "_id")
+				got[i] = id.(string)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sortDocuments(%v) = %v, want %v", tt.sort, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package mongoFake
+
+import (
+	"errors"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	mongoWrites "github.com/BeeTechHub/go-common/database/mongodb/internal/writes"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bulkFailure converts the error of the record at index into a failure; errors other than write errors
+// are also returned, as they fail the whole chunk on a server
+func bulkFailure(index int, err error) (mongodb.BulkFailure, error) {
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) && len(writeException.WriteErrors) > 0 {
+		writeErr := writeException.WriteErrors[0]
+		return mongodb.BulkFailure{Index: index, Code: writeErr.Code, Err: writeErr}, nil
+	}
+	return mongodb.BulkFailure{Index: index, Err: err}, err
+}
+
+// InsertManyChunked inserts records one at a time, as the fake has no round trips to save
+func (c *Collection) InsertManyChunked(sessContext mongo.SessionContext, records []interface{}, opts ...mongodb.BulkOptions) (mongodb.BulkReport, error) {
+	report := mongodb.BulkReport{}
+	errs := []error{}
+	for i, record := range records {
+		if _, err := c.InsertOne(sessContext, record); err != nil {
+			failure, err := bulkFailure(i, err)
+			report.Failures = append(report.Failures, failure)
+			errs = append(errs, err)
+			continue
+		}
+		report.Inserted++
+	}
+	return report, errors.Join(errs...)
+}
+
+// UpsertManyBy upserts records one at a time with the models of mongoWrites.UpsertModel
+func (c *Collection) UpsertManyBy(sessContext mongo.SessionContext, keyFields []string, records []interface{}, opts ...mongodb.BulkOptions) (mongodb.BulkReport, error) {
+	if len(keyFields) == 0 {
+		return mongodb.BulkReport{}, errors.New("mongodb: UpsertManyBy needs at least one key field")
+	}
+
+	report := mongodb.BulkReport{}
+	errs := []error{}
+	for i, record := range records {
+		filter, update, err := mongoWrites.UpsertModel(keyFields, record)
+		if err != nil {
+			// Like the wrapper, records that cannot be converted fail alone
+			report.Failures = append(report.Failures, mongodb.BulkFailure{Index: i, Err: err})
+			continue
+		}
+
+		result, err := c.UpdateOne(sessContext, filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			failure, err := bulkFailure(i, err)
+			report.Failures = append(report.Failures, failure)
+			errs = append(errs, err)
+			continue
+		}
+		report.Matched += result.MatchedCount
+		report.Modified += result.ModifiedCount
+		report.Upserted += result.UpsertedCount
+	}
+	return report, errors.Join(errs...)
+}
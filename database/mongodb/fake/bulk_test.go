@@ -0,0 +1,76 @@
+package mongoFake
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type bulkRecord struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Email string             `bson:"email"`
+	Name  string             `bson:"name"`
+}
+
+func TestUpsertManyBy(t *testing.T) {
+	c := NewCollection("users")
+	if _, err := c.EnsureIndexes(nil, []mongo.IndexModel{{Keys: bson.D{{Key: "email", Value: 1}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.InsertOne(nil, bulkRecord{ID: primitive.NewObjectID(), Email: "a@x.io", Name: "old"}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.UpsertManyBy(nil, []string{"email"}, []interface{}{
+		// Zero ids are left to the server
+		bulkRecord{Email: "a@x.io", Name: "alice"},
+		bulkRecord{Email: "b@x.io", Name: "bob"},
+		bson.M{"name": "no key"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Matched != 1 || report.Modified != 1 || report.Upserted != 1 {
+		t.Errorf("report = %+v, want 1 matched, modified and upserted", report)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Index != 2 {
+		t.Errorf("failures = %+v, want the record without key", report.Failures)
+	}
+
+	records := []bulkRecord{}
+	if err := c.FindMany(nil, &records, bson.M{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if record.ID.IsZero() {
+			t.Errorf("%s was stored with a zero _id", record.Email)
+		}
+	}
+	if len(records) != 2 {
+		t.Errorf("found %d records, want 2", len(records))
+	}
+}
+
+func TestInsertManyChunked(t *testing.T) {
+	c := NewCollection("users")
+	id := primitive.NewObjectID()
+
+	report, err := c.InsertManyChunked(nil, []interface{}{
+		bulkRecord{ID: id, Email: "a@x.io"},
+		bulkRecord{ID: id, Email: "b@x.io"},
+		bulkRecord{ID: primitive.NewObjectID(), Email: "c@x.io"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Inserted != 2 {
+		t.Errorf("inserted = %d, want 2", report.Inserted)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Index != 1 || report.Failures[0].Code != 11000 {
+		t.Errorf("failures = %+v, want a duplicate key at index 1", report.Failures)
+	}
+}
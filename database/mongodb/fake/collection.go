@@ -0,0 +1,955 @@
+package mongoFake
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	"github.com/BeeTechHub/go-common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type index struct {
+	Name   string
+	Keys   bson.D
+	Unique bool
+	Sparse bool
+	// Options renders the other declared options, only to compare declarations
+	Options string
+}
+
+// Collection is an in-memory mongodb.Collection for unit tests. It supports the common query operators
+// ($eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $regex, $elemMatch, $size, $all, $not, $and, $or, $nor),
+// update operators ($set, $unset, $inc, $min, $max, $push, $addToSet, $pull, $setOnInsert, $currentDate),
+// sort, skip, limit, inclusion/exclusion projections, unique indexes and the aggregation stages
+// $match, $sort, $skip, $limit, $project, $unset, $count, $unwind and $facet. Other operators return an error.
+// Session contexts are ignored: writes are applied immediately, there are no transactions.
+type Collection struct {
+	name string
+
+	mu      sync.Mutex
+	docs    []bson.D
+	indexes []index
+	events  []bson.D
+	changed chan struct{}
+}
+
+var _ mongodb.Collection = (*Collection)(nil)
+
+func NewCollection(name string) *Collection {
+	return &Collection{name: name, changed: make(chan struct{})}
+}
+
+// Documents returns a copy of the stored documents in insertion order
+func (c *Collection) Documents() []bson.D {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs := make([]bson.D, len(c.docs))
+	for i, doc := range c.docs {
+		docs[i] = cloneDocument(doc)
+	}
+	return docs
+}
+
+func duplicateKeyError(collection string, index string, position int) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Index:   position,
+		Code:    11000,
+		Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", collection, index),
+	}}}
+}
+
+// checkUnique returns a duplicate key error if doc, stored at position skip (-1 for a new document), breaks a unique index
+func (c *Collection) checkUnique(doc bson.D, skip int) error {
+	indexes := append([]index{{Name: "_id_", Keys: bson.D{{Key: "_id", Value: int32(1)}}, Unique: true}}, c.indexes...)
+	for _, idx := range indexes {
+		if !idx.Unique {
+			continue
+		}
+
+		key, present := indexKey(doc, idx.Keys)
+		if idx.Sparse && !present {
+			continue
+		}
+		for i, other := range c.docs {
+			otherKey, _ := indexKey(other, idx.Keys)
+			if i != skip && equalValues(key, otherKey) {
+				return duplicateKeyError(c.name, idx.Name, 0)
+			}
+		}
+	}
+	return nil
+}
+
+// indexKey returns the values of the index keys in doc, and whether any of them is present
+func indexKey(doc bson.D, keys bson.D) (bson.A, bool) {
+	key := bson.A{}
+	present := false
+	for _, field := range keys {
+		value, ok := getPath(doc, strings.Split(field.Key, "."))
+		key = append(key, value)
+		present = present || ok
+	}
+	return key, present
+}
+
+// find returns the positions of the documents matching filter, in sort order
+func (c *Collection) find(filter bson.D, sort bson.D) ([]int, error) {
+	positions := []int{}
+	for i, doc := range c.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			positions = append(positions, i)
+		}
+	}
+
+	if len(sort) > 0 {
+		slices.SortStableFunc(positions, func(a int, b int) int {
+			return compareDocuments(c.docs[a], c.docs[b], sort)
+		})
+	}
+	return positions, nil
+}
+
+type findOptions struct {
+	sort       bson.D
+	skip       int64
+	limit      int64
+	projection bson.D
+}
+
+func newFindOptions(sort any, skip *int64, limit *int64, projection any) (findOptions, error) {
+	opts := findOptions{}
+	var err error
+	if sort != nil {
+		if opts.sort, err = toDocument(sort); err != nil {
+			return opts, err
+		}
+	}
+	if projection != nil {
+		if opts.projection, err = toDocument(projection); err != nil {
+			return opts, err
+		}
+	}
+	if skip != nil {
+		opts.skip = *skip
+	}
+	if limit != nil {
+		opts.limit = *limit
+		if opts.limit < 0 {
+			opts.limit = -opts.limit
+		}
+	}
+	return opts, nil
+}
+
+func mergeFindOptions(opts []*options.FindOptions) (findOptions, error) {
+	merged := options.Find()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			merged.Sort = opt.Sort
+		}
+		if opt.Skip != nil {
+			merged.Skip = opt.Skip
+		}
+		if opt.Limit != nil {
+			merged.Limit = opt.Limit
+		}
+		if opt.Projection != nil {
+			merged.Projection = opt.Projection
+		}
+	}
+	return newFindOptions(merged.Sort, merged.Skip, merged.Limit, merged.Projection)
+}
+
+// query returns copies of the documents matching filter, after sort, skip, limit and projection
+func (c *Collection) query(filter any, opts findOptions) ([]bson.D, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	positions, err := c.find(filterDoc, opts.sort)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]bson.D, len(positions))
+	for i, p := range positions {
+		docs[i] = c.docs[p]
+	}
+	docs = skipAndLimit(docs, opts.skip, opts.limit)
+
+	result := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		projected, err := project(cloneDocument(doc), opts.projection)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, projected)
+	}
+	return result, nil
+}
+
+func (c *Collection) Count(sessContext mongo.SessionContext, filter bson.M, opts ...*options.CountOptions) (int64, error) {
+	var skip, limit *int64
+	for _, opt := range opts {
+		if opt != nil && opt.Skip != nil {
+			skip = opt.Skip
+		}
+		if opt != nil && opt.Limit != nil {
+			limit = opt.Limit
+		}
+	}
+
+	findOpts, err := newFindOptions(nil, skip, limit, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	docs, err := c.query(filter, findOpts)
+	return int64(len(docs)), err
+}
+
+func (c *Collection) FindOne(sessContext mongo.SessionContext, record any, filter bson.M, opts ...*options.FindOneOptions) error {
+	merged := options.FindOne()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			merged.Sort = opt.Sort
+		}
+		if opt.Skip != nil {
+			merged.Skip = opt.Skip
+		}
+		if opt.Projection != nil {
+			merged.Projection = opt.Projection
+		}
+	}
+
+	limit := int64(1)
+	findOpts, err := newFindOptions(merged.Sort, merged.Skip, &limit, merged.Projection)
+	if err != nil {
+		return err
+	}
+
+	docs, err := c.query(filter, findOpts)
+	if err != nil {
+		return err
+	} else if len(docs) == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return decodeDocument(docs[0], record)
+}
+
+func (c *Collection) FindMany(sessContext mongo.SessionContext, records any, filter bson.M, opts ...*options.FindOptions) error {
+	findOpts, err := mergeFindOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	docs, err := c.query(filter, findOpts)
+	if err != nil {
+		return err
+	}
+	return decodeDocuments(docs, records)
+}
+
+func (c *Collection) FindEach(sessContext mongo.SessionContext, filter bson.M, handle func(doc bson.Raw) error, opts ...*options.FindOptions) error {
+	findOpts, err := mergeFindOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	docs, err := c.query(filter, findOpts)
+	if err != nil {
+		return err
+	}
+	return eachDocument(docs, handle)
+}
+
+func eachDocument(docs []bson.D, handle func(doc bson.Raw) error) error {
+	for _, doc := range docs {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := handle(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collection) FindOneById(sessContext mongo.SessionContext, record any, id primitive.ObjectID) error {
+	return c.FindOne(sessContext, record, bson.M{"_id": id})
+}
+
+func (c *Collection) FindManyByIds(sessContext mongo.SessionContext, records any, ids []primitive.ObjectID, opts ...*options.FindOptions) error {
+	return c.FindMany(sessContext, records, bson.M{"_id": bson.M{"$in": ids}}, opts...)
+}
+
+// FindWithCursor pages with the same keyset filters and tokens as mongodb.MongoCollectionWrapper
+func (c *Collection) FindWithCursor(sessContext mongo.SessionContext, records any, cursor string, size int64, filter bson.M, sortParams bson.D) (mongodb.CursorPage, error) {
+	find := func(query bson.M, sort bson.D, limit int64) ([]bson.Raw, error) {
+		docs, err := c.query(query, findOptions{sort: sort, limit: limit})
+		if err != nil {
+			return nil, err
+		}
+
+		raws := make([]bson.Raw, 0, len(docs))
+		return raws, eachDocument(docs, func(doc bson.Raw) error {
+			raws = append(raws, doc)
+			return nil
+		})
+	}
+
+	return mongodb.PaginateWithCursor(find, records, cursor, size, filter, sortParams)
+}
+
+// aggregate runs pipeline on a copy of the documents
+func (c *Collection) aggregate(pipeline []bson.M) ([]bson.D, error) {
+	stages := make(bson.A, len(pipeline))
+	for i, stage := range pipeline {
+		stages[i] = stage
+	}
+	converted, err := toValue(stages)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	docs := make([]bson.D, len(c.docs))
+	for i, doc := range c.docs {
+		docs[i] = cloneDocument(doc)
+	}
+	c.mu.Unlock()
+
+	return aggregate(docs, converted.(bson.A))
+}
+
+func (c *Collection) FindManyWithAggregation(sessContext mongo.SessionContext, records any, pipeline []bson.M, opts ...*options.AggregateOptions) error {
+	docs, err := c.aggregate(pipeline)
+	if err != nil {
+		return err
+	}
+	return decodeDocuments(docs, records)
+}
+
+func (c *Collection) AggregateEach(sessContext mongo.SessionContext, pipeline []bson.M, handle func(doc bson.Raw) error, opts ...*options.AggregateOptions) error {
+	docs, err := c.aggregate(pipeline)
+	if err != nil {
+		return err
+	}
+	return eachDocument(docs, handle)
+}
+
+func (c *Collection) AggregatePaginated(sessContext mongo.SessionContext, records any, page int64, size int64, pipeline []bson.M, sortParams bson.D, opts ...*options.AggregateOptions) (int64, error) {
+	docs, err := c.aggregate(pipeline)
+	if err != nil {
+		return 0, err
+	} else if len(docs) == 0 {
+		return 0, nil
+	}
+
+	if len(sortParams) == 0 {
+		sortParams = bson.D{{Key: "_id", Value: -1}}
+	}
+	sort, err := toDocument(sortParams)
+	if err != nil {
+		return 0, err
+	}
+	sortDocuments(docs, sort)
+
+	count := int64(len(docs))
+	return count, decodeDocuments(skipAndLimit(docs, utils.CalculatePaginatedSkip(page, size), size), records)
+}
+
+func (c *Collection) FindPaginated(sessContext mongo.SessionContext, records any, page int64, size int64, filter bson.M, sortParams bson.D, pipe ...bson.M) (int64, error) {
+	pipeline := append([]bson.M{{"$match": filter}}, pipe...)
+	return c.AggregatePaginated(sessContext, records, page, size, pipeline, sortParams)
+}
+
+// insert stores doc, adding an ObjectID _id when missing, and returns the _id
+func (c *Collection) insert(doc bson.D, position int) (any, error) {
+	id, ok := lookupField(doc, "_id")
+	if !ok {
+		id = primitive.NewObjectID()
+		doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+	}
+
+	if err := c.checkUnique(doc, -1); err != nil {
+		var writeException mongo.WriteException
+		if errors.As(err, &writeException) {
+			writeException.WriteErrors[0].Index = position
+			return nil, writeException
+		}
+		return nil, err
+	}
+
+	c.docs = append(c.docs, doc)
+	c.emit("insert", id, doc, nil)
+	return id, nil
+}
+
+func (c *Collection) InsertOne(sessContext mongo.SessionContext, newRecord interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := toDocument(newRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.insert(doc, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+func (c *Collection) InsertMany(sessContext mongo.SessionContext, newRecords []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+
+	docs := make([]bson.D, len(newRecords))
+	for i, record := range newRecords {
+		doc, err := toDocument(record)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &mongo.InsertManyResult{}
+	var writeErrors []mongo.BulkWriteError
+	for i, doc := range docs {
+		id, err := c.insert(doc, i)
+		var writeException mongo.WriteException
+		if errors.As(err, &writeException) {
+			writeErrors = append(writeErrors, mongo.BulkWriteError{WriteError: writeException.WriteErrors[0]})
+			if ordered {
+				break
+			}
+			continue
+		} else if err != nil {
+			return result, err
+		}
+		result.InsertedIDs = append(result.InsertedIDs, id)
+	}
+
+	if len(writeErrors) > 0 {
+		return result, mongo.BulkWriteException{WriteErrors: writeErrors}
+	}
+	return result, nil
+}
+
+// update applies update to the first (or every) document matching filter, upserting when asked
+func (c *Collection) update(filter bson.D, update bson.D, many bool, upsert bool) (*mongo.UpdateResult, error) {
+	positions, err := c.find(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !many && len(positions) > 1 {
+		positions = positions[:1]
+	}
+
+	result := &mongo.UpdateResult{MatchedCount: int64(len(positions))}
+	for _, p := range positions {
+		updated, err := applyUpdate(c.docs[p], update, false)
+		if err != nil {
+			return nil, err
+		}
+		if !equalValues(c.docs[p], updated) {
+			result.ModifiedCount++
+		}
+		if err := c.replaceAt(p, updated, "update"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(positions) == 0 && upsert {
+		seed, err := upsertSeed(filter)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := applyUpdate(seed, update, true)
+		if err != nil {
+			return nil, err
+		}
+		if result.UpsertedID, err = c.insert(doc, 0); err != nil {
+			return nil, err
+		}
+		result.UpsertedCount = 1
+	}
+	return result, nil
+}
+
+// replaceAt stores doc at position p, emitting an update or replace event, unless it breaks a unique index
+func (c *Collection) replaceAt(p int, doc bson.D, operationType string) error {
+	if err := c.checkUnique(doc, p); err != nil {
+		return err
+	}
+
+	previous := c.docs[p]
+	if equalValues(previous, doc) {
+		return nil
+	}
+
+	c.docs[p] = doc
+	id, _ := lookupField(doc, "_id")
+	if operationType == "update" {
+		c.emit(operationType, id, doc, updateDescription(previous, doc))
+	} else {
+		c.emit(operationType, id, doc, nil)
+	}
+	return nil
+}
+
+func upsertOption(values ...*bool) bool {
+	upsert := false
+	for _, value := range values {
+		if value != nil {
+			upsert = *value
+		}
+	}
+	return upsert
+}
+
+func (c *Collection) updateWithOptions(filter bson.M, update bson.M, many bool, opts []*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	updateDoc, err := toDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	upserts := []*bool{}
+	for _, opt := range opts {
+		if opt != nil {
+			upserts = append(upserts, opt.Upsert)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.update(filterDoc, updateDoc, many, upsertOption(upserts...))
+}
+
+func (c *Collection) UpdateOne(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateWithOptions(filter, update, false, opts)
+}
+
+func (c *Collection) UpdateMany(sessContext mongo.SessionContext, filter bson.M, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.updateWithOptions(filter, update, true, opts)
+}
+
+// replace replaces the first document matching filter, keeping its _id
+func (c *Collection) replace(filter bson.D, replacement bson.D, upsert bool) (*mongo.UpdateResult, error) {
+	if isOperatorDocument(replacement) {
+		return nil, fmt.Errorf("mongoFake: replacement document cannot contain update operators")
+	}
+
+	positions, err := c.find(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mongo.UpdateResult{}
+	if len(positions) == 0 {
+		if upsert {
+			seed, err := upsertSeed(filter)
+			if err != nil {
+				return nil, err
+			}
+			doc := replacement
+			if id, ok := lookupField(seed, "_id"); ok && !hasField(replacement, "_id") {
+				doc = append(bson.D{{Key: "_id", Value: id}}, replacement...)
+			}
+			if result.UpsertedID, err = c.insert(doc, 0); err != nil {
+				return nil, err
+			}
+			result.UpsertedCount = 1
+		}
+		return result, nil
+	}
+
+	p := positions[0]
+	id, _ := lookupField(c.docs[p], "_id")
+	doc := bson.D{{Key: "_id", Value: id}}
+	for _, e := range replacement {
+		if e.Key == "_id" {
+			if !equalValues(e.Value, id) {
+				return nil, fmt.Errorf("mongoFake: the _id field cannot be updated")
+			}
+			continue
+		}
+		doc = append(doc, e)
+	}
+
+	result.MatchedCount = 1
+	if !equalValues(c.docs[p], doc) {
+		result.ModifiedCount = 1
+	}
+	return result, c.replaceAt(p, doc, "replace")
+}
+
+func hasField(doc bson.D, key string) bool {
+	_, ok := lookupField(doc, key)
+	return ok
+}
+
+func (c *Collection) ReplaceOne(sessContext mongo.SessionContext, filter bson.M, updateRecord any, opts ...*options.ReplaceOptions) error {
+	_, err := c.replaceOne(filter, updateRecord, opts...)
+	return err
+}
+
+func (c *Collection) replaceOne(filter bson.M, updateRecord any, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := toDocument(updateRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	upserts := []*bool{}
+	for _, opt := range opts {
+		if opt != nil {
+			upserts = append(upserts, opt.Upsert)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.replace(filterDoc, replacement, upsertOption(upserts...))
+}
+
+type findAndModifyOptions struct {
+	sort       any
+	projection any
+	upsert     bool
+	after      bool
+}
+
+// findAndModify modifies the first document matching filter in sort order and decodes it, before or after the change
+func (c *Collection) findAndModify(record any, filter bson.M, opts findAndModifyOptions, modify func(filter bson.D) (*mongo.UpdateResult, error)) error {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return err
+	}
+	findOpts, err := newFindOptions(opts.sort, nil, nil, opts.projection)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	positions, err := c.find(filterDoc, findOpts.sort)
+	if err != nil {
+		return err
+	}
+
+	var before bson.D
+	target := filterDoc
+	if len(positions) > 0 {
+		before = c.docs[positions[0]]
+		id, _ := lookupField(before, "_id")
+		target = bson.D{{Key: "_id", Value: id}}
+	} else if !opts.upsert {
+		return mongo.ErrNoDocuments
+	}
+
+	result, err := modify(target)
+	if err != nil {
+		return err
+	}
+
+	doc := before
+	if opts.after {
+		id, _ := lookupField(before, "_id")
+		if result.UpsertedID != nil {
+			id = result.UpsertedID
+		}
+		for _, stored := range c.docs {
+			if storedID, _ := lookupField(stored, "_id"); equalValues(storedID, id) {
+				doc = stored
+			}
+		}
+	}
+	if doc == nil {
+		return mongo.ErrNoDocuments
+	}
+
+	projected, err := project(cloneDocument(doc), findOpts.projection)
+	if err != nil {
+		return err
+	}
+	return decodeDocument(projected, record)
+}
+
+func (c *Collection) FindOneAndUpdate(sessContext mongo.SessionContext, record any, filter bson.M, update bson.M, opts ...*options.FindOneAndUpdateOptions) error {
+	updateDoc, err := toDocument(update)
+	if err != nil {
+		return err
+	}
+
+	modifyOpts := findAndModifyOptions{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			modifyOpts.sort = opt.Sort
+		}
+		if opt.Projection != nil {
+			modifyOpts.projection = opt.Projection
+		}
+		if opt.Upsert != nil {
+			modifyOpts.upsert = *opt.Upsert
+		}
+		if opt.ReturnDocument != nil {
+			modifyOpts.after = *opt.ReturnDocument == options.After
+		}
+	}
+
+	return c.findAndModify(record, filter, modifyOpts, func(target bson.D) (*mongo.UpdateResult, error) {
+		return c.update(target, updateDoc, false, modifyOpts.upsert)
+	})
+}
+
+func (c *Collection) FindOneAndReplace(sessContext mongo.SessionContext, returnRecord any, filter bson.M, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
+	replacement, err := toDocument(updateRecord)
+	if err != nil {
+		return err
+	}
+
+	modifyOpts := findAndModifyOptions{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if opt.Sort != nil {
+			modifyOpts.sort = opt.Sort
+		}
+		if opt.Projection != nil {
+			modifyOpts.projection = opt.Projection
+		}
+		if opt.Upsert != nil {
+			modifyOpts.upsert = *opt.Upsert
+		}
+		if opt.ReturnDocument != nil {
+			modifyOpts.after = *opt.ReturnDocument == options.After
+		}
+	}
+
+	return c.findAndModify(returnRecord, filter, modifyOpts, func(target bson.D) (*mongo.UpdateResult, error) {
+		return c.replace(target, replacement, modifyOpts.upsert)
+	})
+}
+
+// delete removes the first (or every) document matching filter
+func (c *Collection) delete(filter bson.D, many bool) (int64, error) {
+	positions, err := c.find(filter, nil)
+	if err != nil {
+		return 0, err
+	}
+	if !many && len(positions) > 1 {
+		positions = positions[:1]
+	}
+
+	removed := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		removed[p] = true
+		id, _ := lookupField(c.docs[p], "_id")
+		c.emit("delete", id, nil, nil)
+	}
+
+	kept := make([]bson.D, 0, len(c.docs)-len(positions))
+	for i, doc := range c.docs {
+		if !removed[i] {
+			kept = append(kept, doc)
+		}
+	}
+	c.docs = kept
+	return int64(len(positions)), nil
+}
+
+func (c *Collection) deleteMatching(filter any, many bool) (int64, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.delete(filterDoc, many)
+}
+
+func (c *Collection) DeleteMany(sessContext mongo.SessionContext, filter bson.M, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	deleted, err := c.deleteMatching(filter, true)
+	if err != nil {
+		return nil, err
+	}
+	return &mongo.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (c *Collection) DeleteOneById(sessContext mongo.SessionContext, id primitive.ObjectID) error {
+	_, err := c.deleteMatching(bson.M{"_id": id}, true)
+	return err
+}
+
+func (c *Collection) DeleteManyByIds(sessContext mongo.SessionContext, ids []primitive.ObjectID) error {
+	_, err := c.deleteMatching(bson.M{"_id": bson.M{"$in": ids}}, true)
+	return err
+}
+
+// BulkWrite applies the insert, update, replace and delete models in order. Unordered writes are also applied in order.
+func (c *Collection) BulkWrite(sessContext mongo.SessionContext, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	ordered := true
+	for _, opt := range opts {
+		if opt != nil && opt.Ordered != nil {
+			ordered = *opt.Ordered
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}}
+	var writeErrors []mongo.BulkWriteError
+	for i, model := range models {
+		err := c.applyWriteModel(result, int64(i), model)
+		var writeException mongo.WriteException
+		if errors.As(err, &writeException) {
+			writeError := writeException.WriteErrors[0]
+			writeError.Index = i
+			writeErrors = append(writeErrors, mongo.BulkWriteError{WriteError: writeError, Request: model})
+			if ordered {
+				break
+			}
+			continue
+		} else if err != nil {
+			return result, err
+		}
+	}
+
+	if len(writeErrors) > 0 {
+		return result, mongo.BulkWriteException{WriteErrors: writeErrors}
+	}
+	return result, nil
+}
+
+func (c *Collection) applyWriteModel(result *mongo.BulkWriteResult, i int64, model mongo.WriteModel) error {
+	addUpdate := func(update *mongo.UpdateResult) {
+		result.MatchedCount += update.MatchedCount
+		result.ModifiedCount += update.ModifiedCount
+		result.UpsertedCount += update.UpsertedCount
+		if update.UpsertedID != nil {
+			result.UpsertedIDs[i] = update.UpsertedID
+		}
+	}
+
+	switch model := model.(type) {
+	case *mongo.InsertOneModel:
+		doc, err := toDocument(model.Document)
+		if err != nil {
+			return err
+		}
+		if _, err := c.insert(doc, int(i)); err != nil {
+			return err
+		}
+		result.InsertedCount++
+	case *mongo.UpdateOneModel, *mongo.UpdateManyModel:
+		var filter, update any
+		var upsert *bool
+		many := false
+		if one, ok := model.(*mongo.UpdateOneModel); ok {
+			filter, update, upsert = one.Filter, one.Update, one.Upsert
+		} else {
+			all := model.(*mongo.UpdateManyModel)
+			filter, update, upsert, many = all.Filter, all.Update, all.Upsert, true
+		}
+
+		filterDoc, err := toDocument(filter)
+		if err != nil {
+			return err
+		}
+		updateDoc, err := toDocument(update)
+		if err != nil {
+			return err
+		}
+		updateResult, err := c.update(filterDoc, updateDoc, many, upsertOption(upsert))
+		if err != nil {
+			return err
+		}
+		addUpdate(updateResult)
+	case *mongo.ReplaceOneModel:
+		filterDoc, err := toDocument(model.Filter)
+		if err != nil {
+			return err
+		}
+		replacement, err := toDocument(model.Replacement)
+		if err != nil {
+			return err
+		}
+		updateResult, err := c.replace(filterDoc, replacement, upsertOption(model.Upsert))
+		if err != nil {
+			return err
+		}
+		addUpdate(updateResult)
+	case *mongo.DeleteOneModel, *mongo.DeleteManyModel:
+		var filter any
+		many := false
+		if one, ok := model.(*mongo.DeleteOneModel); ok {
+			filter = one.Filter
+		} else {
+			filter, many = model.(*mongo.DeleteManyModel).Filter, true
+		}
+
+		filterDoc, err := toDocument(filter)
+		if err != nil {
+			return err
+		}
+		deleted, err := c.delete(filterDoc, many)
+		if err != nil {
+			return err
+		}
+		result.DeletedCount += deleted
+	default:
+		return fmt.Errorf("mongoFake: unsupported write model %T", model)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package mongoFake
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type pageRecord struct {
+	ID    int32  `bson:"_id"`
+	Group string `bson:"group"`
+	Rank  *int32 `bson:"rank"`
+}
+
+func newPageCollection(t *testing.T) *Collection {
+	t.Helper()
+
+	c := NewCollection("pages")
+	for i := int32(1); i <= 10; i++ {
+		record := pageRecord{ID: i, Group: "a"}
+		if i%2 == 0 {
+			record.Group = "b"
+		}
+		// Every third record has no rank, to page over null sort values
+		if i%3 != 0 {
+			rank := i % 4
+			record.Rank = &rank
+		}
+		if _, err := c.InsertOne(nil, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func recordIDs(records []pageRecord) []int32 {
+	ids := make([]int32, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	return ids
+}
+
+func TestFindPaginated(t *testing.T) {
+	c := newPageCollection(t)
+
+	tests := []struct {
+		name      string
+		page      int64
+		size      int64
+		filter    bson.M
+		sort      bson.D
+		wantCount int64
+		wantIDs   []int32
+	}{
+		{"default sort is _id descending", 1, 3, bson.M{}, nil, 10, []int32{10, 9, 8}},
+		{"second page", 2, 3, bson.M{}, nil, 10, []int32{7, 6, 5}},
+		{"last partial page", 4, 3, bson.M{}, nil, 10, []int32{1}},
+		{"past the end", 5, 3, bson.M{}, nil, 10, []int32{}},
+		{"filtered", 1, 10, bson.M{"group": "a"}, bson.D{{Key: "_id", Value: 1}}, 5, []int32{1, 3, 5, 7, 9}},
+		{"no match", 1, 10, bson.M{"group": "c"}, nil, 0, []int32{}},
+		{"null ranks first", 1, 4, bson.M{}, bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}, 10, []int32{3, 6, 9, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []pageRecord{}
+			count, err := c.FindPaginated(nil, &records, tt.page, tt.size, tt.filter, tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+			if got := recordIDs(records); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestFindWithCursor(t *testing.T) {
+	c := newPageCollection(t)
+
+	tests := []struct {
+		name string
+		size int64
+		sort bson.D
+	}{
+		{"default", 4, nil},
+		{"_id ascending", 3, bson.D{{Key: "_id", Value: 1}}},
+		{"nullable ascending", 3, bson.D{{Key: "rank", Value: 1}}},
+		{"nullable descending", 2, bson.D{{Key: "rank", Value: -1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := []pageRecord{}
+			if err := c.FindMany(nil, &all, bson.M{}); err != nil {
+				t.Fatal(err)
+			}
+			sorted := []pageRecord{}
+			// Cursor pages break ties on _id descending
+			sortParams := bson.D{{Key: "_id", Value: -1}}
+			if len(tt.sort) > 0 && tt.sort[0].Key != "_id" {
+				sortParams = append(slices.Clone(tt.sort), sortParams...)
+			} else if len(tt.sort) > 0 {
+				sortParams = tt.sort
+			}
+			if _, err := c.FindPaginated(nil, &sorted, 1, int64(len(all)), bson.M{}, sortParams); err != nil {
+				t.Fatal(err)
+			}
+			want := recordIDs(sorted)
+
+			// Forward through every page, then back from the last one
+			got := []int32{}
+			pages := [][]int32{}
+			cursor, lastPrev := "", ""
+			for i := 0; ; i++ {
+				if i > len(all) {
+					t.Fatal("forward paging does not end")
+				}
+				records := []pageRecord{}
+				result, err := c.FindWithCursor(nil, &records, cursor, tt.size, bson.M{}, tt.sort)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(records) > 0 {
+					got = append(got, recordIDs(records)...)
+					pages = append(pages, recordIDs(records))
+					lastPrev = result.PrevCursor
+				}
+				if result.NextCursor == "" {
+					break
+				}
+				cursor = result.NextCursor
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("forward ids = %v, want %v", got, want)
+			}
+
+			cursor = lastPrev
+			for i := len(pages) - 2; i >= 0; i-- {
+				records := []pageRecord{}
+				result, err := c.FindWithCursor(nil, &records, cursor, tt.size, bson.M{}, tt.sort)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ids := recordIDs(records); !slices.Equal(ids, pages[i]) {
+					t.Fatalf("backward page %d = %v, want %v", i, ids, pages[i])
+				}
+				cursor = result.PrevCursor
+			}
+			if cursor != "" {
+				t.Errorf("first page has a previous cursor %q", cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package mongoFake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func declaredIndex(model mongo.IndexModel) (index, error) {
+	keys, err := toDocument(model.Keys)
+	if err != nil {
+		return index{}, err
+	}
+
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+
+	idx := index{Name: strings.Join(parts, "_"), Keys: keys}
+	if model.Options != nil {
+		if model.Options.Name != nil {
+			idx.Name = *model.Options.Name
+		}
+		if model.Options.Unique != nil {
+			idx.Unique = *model.Options.Unique
+		}
+		if model.Options.Sparse != nil {
+			idx.Sparse = *model.Options.Sparse
+		}
+		idx.Options = indexOptions(model.Options)
+	}
+	return idx, nil
+}
+
+// indexOptions renders the hidden, TTL, partial filter and collation options; maps print with sorted keys
+func indexOptions(opts *options.IndexOptions) string {
+	parts := []string{}
+	if opts.Hidden != nil {
+		parts = append(parts, fmt.Sprint("hidden=", *opts.Hidden))
+	}
+	if opts.ExpireAfterSeconds != nil {
+		parts = append(parts, fmt.Sprint("expireAfterSeconds=", *opts.ExpireAfterSeconds))
+	}
+	if opts.PartialFilterExpression != nil {
+		parts = append(parts, fmt.Sprint("partialFilterExpression=", opts.PartialFilterExpression))
+	}
+	if opts.Collation != nil {
+		parts = append(parts, fmt.Sprint("collation=", opts.Collation.ToDocument()))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (idx index) sameAs(other index) bool {
+	if len(idx.Keys) != len(other.Keys) || idx.Unique != other.Unique || idx.Sparse != other.Sparse || idx.Options != other.Options {
+		return false
+	}
+	for i := range idx.Keys {
+		if idx.Keys[i].Key != other.Keys[i].Key || fmt.Sprint(idx.Keys[i].Value) != fmt.Sprint(other.Keys[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// validUnique returns a duplicate key error if the stored documents break the unique index idx
+func (c *Collection) validUnique(idx index) error {
+	if !idx.Unique {
+		return nil
+	}
+
+	for i, doc := range c.docs {
+		key, present := indexKey(doc, idx.Keys)
+		if idx.Sparse && !present {
+			continue
+		}
+		for _, other := range c.docs[i+1:] {
+			if otherKey, _ := indexKey(other, idx.Keys); equalValues(key, otherKey) {
+				return duplicateKeyError(c.name, idx.Name, 0)
+			}
+		}
+	}
+	return nil
+}
+
+// EnsureIndexes reports like mongodb.MongoCollectionWrapper.EnsureIndexes. Only unique indexes have an effect, on writes.
+func (c *Collection) EnsureIndexes(sessContext mongo.SessionContext, models []mongo.IndexModel, opts ...mongodb.EnsureIndexesOptions) (mongodb.IndexReport, error) {
+	var opt mongodb.EnsureIndexesOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	report := mongodb.IndexReport{}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	indexes := []index{}
+	declaredNames := map[string]bool{}
+	for _, model := range models {
+		declared, err := declaredIndex(model)
+		if err != nil {
+			return report, err
+		}
+		declaredNames[declared.Name] = true
+
+		found := false
+		for _, existing := range c.indexes {
+			if existing.Name != declared.Name {
+				continue
+			}
+			found = true
+			if existing.sameAs(declared) {
+				report.Unchanged = append(report.Unchanged, declared.Name)
+			} else {
+				report.Recreated = append(report.Recreated, declared.Name)
+			}
+		}
+		if !found {
+			report.Created = append(report.Created, declared.Name)
+		}
+
+		if err := c.validUnique(declared); err != nil {
+			return report, err
+		}
+		indexes = append(indexes, declared)
+	}
+
+	for _, existing := range c.indexes {
+		if declaredNames[existing.Name] {
+			continue
+		}
+		if opt.DropUnknown {
+			report.Dropped = append(report.Dropped, existing.Name)
+		} else {
+			indexes = append(indexes, existing)
+		}
+	}
+
+	if !opt.DryRun {
+		c.indexes = indexes
+	}
+	return report, nil
+}
+
+// Indexes returns the names and keys of the indexes created with EnsureIndexes, without the _id index
+func (c *Collection) Indexes() map[string]bson.D {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	indexes := make(map[string]bson.D, len(c.indexes))
+	for _, idx := range c.indexes {
+		indexes[idx.Name] = idx.Keys
+	}
+	return indexes
+}
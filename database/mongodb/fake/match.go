@@ -0,0 +1,260 @@
+package mongoFake
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// matches evaluates a query filter on doc
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElement(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchElement(doc bson.D, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+		clauses, ok := e.Value.(bson.A)
+		if !ok || len(clauses) == 0 {
+			return false, fmt.Errorf("mongoFake: %s needs a non-empty array", e.Key)
+		}
+
+		for _, clause := range clauses {
+			filter, ok := clause.(bson.D)
+			if !ok {
+				return false, fmt.Errorf("mongoFake: %s entries must be documents", e.Key)
+			}
+			ok, err := matches(doc, filter)
+			if err != nil {
+				return false, err
+			}
+
+			switch {
+			case e.Key == "$and" && !ok:
+				return false, nil
+			case e.Key == "$or" && ok:
+				return true, nil
+			case e.Key == "$nor" && ok:
+				return false, nil
+			}
+		}
+		return e.Key != "$or", nil
+	case "$comment":
+		return true, nil
+	}
+
+	if strings.HasPrefix(e.Key, "$") {
+		return false, fmt.Errorf("mongoFake: unsupported query operator %s", e.Key)
+	}
+
+	return matchCondition(candidates(doc, e.Key), e.Value)
+}
+
+// matchCondition evaluates the condition of one field, an operator document or a value to compare with
+func matchCondition(values []any, condition any) (bool, error) {
+	if !isOperatorDocument(condition) {
+		if regex, ok := condition.(primitive.Regex); ok {
+			return matchRegex(values, regex)
+		}
+		return matchEq(values, condition), nil
+	}
+
+	operators := condition.(bson.D)
+	for _, operator := range operators {
+		ok, err := matchOperator(values, operator, operators)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchEq(values []any, expected any) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if equalValues(value, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCompare(values []any, expected any, accept func(c int) bool) bool {
+	for _, value := range values {
+		// Like the server, only values of the same type bracket are compared
+		if typeRank(value) == typeRank(expected) && accept(compareValues(value, expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchIn(values []any, list any) (bool, error) {
+	array, ok := list.(bson.A)
+	if !ok {
+		return false, fmt.Errorf("mongoFake: $in and $nin need an array")
+	}
+
+	for _, expected := range array {
+		if regex, ok := expected.(primitive.Regex); ok {
+			if ok, err := matchRegex(values, regex); ok || err != nil {
+				return ok, err
+			}
+		} else if matchEq(values, expected) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchRegex(values []any, regex primitive.Regex) (bool, error) {
+	pattern := regex.Pattern
+	if regex.Options != "" {
+		flags := strings.Map(func(r rune) rune {
+			if strings.ContainsRune("imsU", r) {
+				return r
+			}
+			return -1
+		}, regex.Options)
+		if flags != "" {
+			pattern = "(?" + flags + ")" + pattern
+		}
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	for _, value := range values {
+		if s, ok := value.(string); ok && compiled.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchOperator(values []any, operator bson.E, siblings bson.D) (bool, error) {
+	switch operator.Key {
+	case "$eq":
+		return matchEq(values, operator.Value), nil
+	case "$ne":
+		return !matchEq(values, operator.Value), nil
+	case "$gt":
+		return matchCompare(values, operator.Value, func(c int) bool { return c > 0 }), nil
+	case "$gte":
+		return matchCompare(values, operator.Value, func(c int) bool { return c >= 0 }), nil
+	case "$lt":
+		return matchCompare(values, operator.Value, func(c int) bool { return c < 0 }), nil
+	case "$lte":
+		return matchCompare(values, operator.Value, func(c int) bool { return c <= 0 }), nil
+	case "$in":
+		return matchIn(values, operator.Value)
+	case "$nin":
+		ok, err := matchIn(values, operator.Value)
+		return !ok, err
+	case "$exists":
+		exists, _ := operator.Value.(bool)
+		if n, ok := toInt64(operator.Value); ok {
+			exists = n != 0
+		}
+		return (len(values) > 0) == exists, nil
+	case "$regex":
+		regex, ok := operator.Value.(primitive.Regex)
+		if !ok {
+			pattern, ok := operator.Value.(string)
+			if !ok {
+				return false, fmt.Errorf("mongoFake: $regex needs a string or a regex")
+			}
+			regex = primitive.Regex{Pattern: pattern}
+		}
+		if options, ok := lookupField(siblings, "$options"); ok {
+			regex.Options, _ = options.(string)
+		}
+		return matchRegex(values, regex)
+	case "$options":
+		return true, nil
+	case "$not":
+		ok, err := matchCondition(values, operator.Value)
+		return !ok, err
+	case "$size":
+		size, ok := toInt64(operator.Value)
+		if !ok {
+			return false, fmt.Errorf("mongoFake: $size needs an integer")
+		}
+		for _, value := range values {
+			if array, ok := value.(bson.A); ok && int64(len(array)) == size {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$all":
+		array, ok := operator.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("mongoFake: $all needs an array")
+		}
+		for _, expected := range array {
+			if !matchEq(values, expected) {
+				return false, nil
+			}
+		}
+		return len(array) > 0, nil
+	case "$elemMatch":
+		return matchElemMatch(values, operator.Value)
+	}
+
+	return false, fmt.Errorf("mongoFake: unsupported query operator %s", operator.Key)
+}
+
+// matchElemMatch matches arrays with an element satisfying every condition, either field conditions
+// on document elements or operators on the elements themselves
+func matchElemMatch(values []any, condition any) (bool, error) {
+	filter, ok := condition.(bson.D)
+	if !ok {
+		return false, fmt.Errorf("mongoFake: $elemMatch needs a document")
+	}
+	onElements := isOperatorDocument(filter) && filter[0].Key != "$and" && filter[0].Key != "$or" && filter[0].Key != "$nor"
+
+	for _, value := range values {
+		array, ok := value.(bson.A)
+		if !ok {
+			continue
+		}
+
+		for _, elem := range array {
+			var ok bool
+			var err error
+			if onElements {
+				ok, err = matchCondition(candidatesOf(elem), filter)
+			} else if doc, isDoc := elem.(bson.D); isDoc {
+				ok, err = matches(doc, filter)
+			}
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func candidatesOf(value any) []any {
+	values := []any{value}
+	if array, ok := value.(bson.A); ok {
+		values = append(values, array...)
+	}
+	return values
+}
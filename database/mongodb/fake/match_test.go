@@ -0,0 +1,103 @@
+package mongoFake
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMatches(t *testing.T) {
+	doc := bson.D{
+		{Key: "name", Value: "alice"},
+		{Key: "age", Value: int32(30)},
+		{Key: "score", Value: 7.5},
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "address", Value: bson.D{{Key: "city", Value: "Hanoi"}}},
+		{Key: "items", Value: bson.A{
+			bson.D{{Key: "sku", Value: "x"}, {Key: "qty", Value: int32(1)}},
+			bson.D{{Key: "sku", Value: "y"}, {Key: "qty", Value: int32(5)}},
+		}},
+		{Key: "deletedAt", Value: nil},
+	}
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   bool
+	}{
+		{"equality", bson.M{"name": "alice"}, true},
+		{"equality mismatch", bson.M{"name": "bob"}, false},
+		{"int64 equals int32", bson.M{"age": int64(30)}, true},
+		{"float equals int", bson.M{"age": 30.0}, true},
+		{"$ne", bson.M{"name": bson.M{"$ne": "bob"}}, true},
+		{"$gt", bson.M{"age": bson.M{"$gt": 29}}, true},
+		{"$gte", bson.M{"age": bson.M{"$gte": 30}}, true},
+		{"$lt", bson.M{"score": bson.M{"$lt": 7.5}}, false},
+		{"$lte", bson.M{"score": bson.M{"$lte": 7.5}}, true},
+		{"$gt across types", bson.M{"name": bson.M{"$gt": 1}}, false},
+		{"$in", bson.M{"name": bson.M{"$in": bson.A{"bob", "alice"}}}, true},
+		{"$nin", bson.M{"name": bson.M{"$nin": bson.A{"bob", "alice"}}}, false},
+		{"$exists", bson.M{"missing": bson.M{"$exists": false}}, true},
+		{"$exists on null", bson.M{"deletedAt": bson.M{"$exists": true}}, true},
+		{"null matches null", bson.M{"deletedAt": nil}, true},
+		{"null matches missing", bson.M{"missing": nil}, true},
+		{"array element", bson.M{"tags": "b"}, true},
+		{"whole array", bson.M{"tags": bson.A{"a", "b"}}, true},
+		{"$all", bson.M{"tags": bson.M{"$all": bson.A{"a", "b"}}}, true},
+		{"$size", bson.M{"tags": bson.M{"$size": 2}}, true},
+		{"dotted path", bson.M{"address.city": "Hanoi"}, true},
+		{"dotted path in array", bson.M{"items.sku": "y"}, true},
+		{"array index", bson.M{"items.0.sku": "y"}, false},
+		{"$elemMatch", bson.M{"items": bson.M{"$elemMatch": bson.M{"sku": "x", "qty": bson.M{"$gt": 2}}}}, false},
+		{"$elemMatch element", bson.M{"items": bson.M{"$elemMatch": bson.M{"sku": "y", "qty": bson.M{"$gt": 2}}}}, true},
+		{"$regex", bson.M{"name": bson.M{"$regex": "^AL", "$options": "i"}}, true},
+		{"regex value", bson.M{"name": primitive.Regex{Pattern: "ice$"}}, true},
+		{"$not", bson.M{"age": bson.M{"$not": bson.M{"$gt": 40}}}, true},
+		{"$and", bson.M{"$and": bson.A{bson.M{"name": "alice"}, bson.M{"age": 31}}}, false},
+		{"$or", bson.M{"$or": bson.A{bson.M{"name": "bob"}, bson.M{"age": 30}}}, true},
+		{"$nor", bson.M{"$nor": bson.A{bson.M{"name": "bob"}, bson.M{"age": 31}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := toDocument(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := matches(doc, filter)
+			if err != nil {
+				t.Fatalf("matches returned %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.M
+	}{
+		{"unknown top-level operator", bson.M{"$where": "true"}},
+		{"unknown field operator", bson.M{"name": bson.M{"$near": bson.A{0, 0}}}},
+		{"empty $or", bson.M{"$or": bson.A{}}},
+		{"$size without integer", bson.M{"tags": bson.M{"$size": "2"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := toDocument(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := matches(bson.D{{Key: "name", Value: "alice"}}, filter); err == nil {
+				t.Errorf("matches(%v) returned no error", tt.filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package mongoFake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applyUpdate applies update operators to a copy of doc. $setOnInsert is applied only when inserting.
+func applyUpdate(doc bson.D, update bson.D, inserting bool) (bson.D, error) {
+	if len(update) == 0 || !isOperatorDocument(update) {
+		return nil, fmt.Errorf("mongoFake: update document must only contain update operators")
+	}
+
+	result := cloneDocument(doc)
+	for _, operator := range update {
+		fields, ok := operator.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: %s needs a document", operator.Key)
+		}
+
+		for _, field := range fields {
+			if id, ok := lookupField(result, "_id"); ok && field.Key == "_id" && operator.Key != "$setOnInsert" && !equalValues(id, field.Value) {
+				return nil, fmt.Errorf("mongoFake: the _id field cannot be updated")
+			}
+
+			var err error
+			result, err = applyOperator(result, operator.Key, field.Key, field.Value, inserting)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func applyOperator(doc bson.D, operator string, path string, value any, inserting bool) (bson.D, error) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts {
+		if strings.HasPrefix(part, "$") {
+			return nil, fmt.Errorf("mongoFake: positional operator in %q is not supported", path)
+		}
+	}
+	current, exists := getPath(doc, parts)
+
+	switch operator {
+	case "$set":
+		return setPath(doc, parts, value)
+	case "$setOnInsert":
+		if !inserting {
+			return doc, nil
+		}
+		return setPath(doc, parts, value)
+	case "$unset":
+		return unsetPath(doc, parts), nil
+	case "$inc":
+		if !exists {
+			current = int32(0)
+		}
+		sum, err := addNumbers(current, value)
+		if err != nil {
+			return nil, fmt.Errorf("mongoFake: $inc on %q: %w", path, err)
+		}
+		return setPath(doc, parts, sum)
+	case "$min", "$max":
+		c := compareValues(value, current)
+		if !exists || (operator == "$min" && c < 0) || (operator == "$max" && c > 0) {
+			return setPath(doc, parts, value)
+		}
+		return doc, nil
+	case "$currentDate":
+		return setPath(doc, parts, primitive.NewDateTimeFromTime(time.Now()))
+	case "$push", "$addToSet":
+		array := bson.A{}
+		if exists {
+			existing, ok := current.(bson.A)
+			if !ok {
+				return nil, fmt.Errorf("mongoFake: %s on %q which is not an array", operator, path)
+			}
+			array = append(array, existing...)
+		}
+
+		items := bson.A{value}
+		if each, ok := value.(bson.D); ok && len(each) > 0 && each[0].Key == "$each" {
+			if items, ok = each[0].Value.(bson.A); !ok {
+				return nil, fmt.Errorf("mongoFake: $each needs an array")
+			}
+		}
+
+		for _, item := range items {
+			if operator == "$addToSet" && matchEq([]any(array), item) {
+				continue
+			}
+			array = append(array, item)
+		}
+		return setPath(doc, parts, array)
+	case "$pull":
+		if !exists {
+			return doc, nil
+		}
+		existing, ok := current.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("mongoFake: $pull on %q which is not an array", path)
+		}
+
+		kept := bson.A{}
+		for _, elem := range existing {
+			var remove bool
+			var err error
+			if condition, isDoc := value.(bson.D); isDoc && isOperatorDocument(condition) {
+				remove, err = matchCondition(candidatesOf(elem), condition)
+			} else if isDoc {
+				elemDoc, elemIsDoc := elem.(bson.D)
+				if elemIsDoc {
+					remove, err = matches(elemDoc, condition)
+				}
+			} else {
+				remove = equalValues(elem, value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !remove {
+				kept = append(kept, elem)
+			}
+		}
+		return setPath(doc, parts, kept)
+	}
+
+	return nil, fmt.Errorf("mongoFake: unsupported update operator %s", operator)
+}
+
+func addNumbers(a any, b any) (any, error) {
+	if typeRank(a) != 2 || typeRank(b) != 2 {
+		return nil, fmt.Errorf("cannot add %T and %T", a, b)
+	}
+
+	if intA, ok := toInt64(a); ok {
+		if intB, ok := toInt64(b); ok {
+			_, a32 := a.(int32)
+			_, b32 := b.(int32)
+			sum := intA + intB
+			if a32 && b32 && sum == int64(int32(sum)) {
+				return int32(sum), nil
+			}
+			return sum, nil
+		}
+	}
+	return toFloat(a) + toFloat(b), nil
+}
+
+// getPath returns the value at path without traversing arrays, except through numeric indexes
+func getPath(value any, parts []string) (any, bool) {
+	for _, part := range parts {
+		switch v := value.(type) {
+		case bson.D:
+			child, ok := lookupField(v, part)
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case bson.A:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// setPath sets the value at path, creating the missing intermediate documents
+func setPath(doc bson.D, parts []string, value any) (bson.D, error) {
+	result, err := setValue(doc, parts, value)
+	if err != nil {
+		return nil, err
+	}
+	return result.(bson.D), nil
+}
+
+func setValue(container any, parts []string, value any) (any, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+
+	switch v := container.(type) {
+	case bson.D:
+		for i, e := range v {
+			if e.Key == parts[0] {
+				child, err := setValue(e.Value, parts[1:], value)
+				if err != nil {
+					return nil, err
+				}
+				v[i].Value = child
+				return v, nil
+			}
+		}
+
+		child, err := setValue(bson.D{}, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(v, bson.E{Key: parts[0], Value: child}), nil
+	case bson.A:
+		i, err := strconv.Atoi(parts[0])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("mongoFake: cannot create field %q in an array", parts[0])
+		}
+		for len(v) <= i {
+			v = append(v, nil)
+		}
+
+		var existing any = bson.D{}
+		if v[i] != nil || len(parts) == 1 {
+			existing = v[i]
+		}
+		child, err := setValue(existing, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = child
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("mongoFake: cannot create field %q in element %v", parts[0], container)
+}
+
+func unsetPath(doc bson.D, parts []string) bson.D {
+	result, _ := unsetValue(doc, parts).(bson.D)
+	return result
+}
+
+func unsetValue(container any, parts []string) any {
+	switch v := container.(type) {
+	case bson.D:
+		for i, e := range v {
+			if e.Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				return append(v[:i:i], v[i+1:]...)
+			}
+			v[i].Value = unsetValue(e.Value, parts[1:])
+			return v
+		}
+	case bson.A:
+		i, err := strconv.Atoi(parts[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return v
+		}
+		if len(parts) == 1 {
+			// Like the server, an unset array element becomes null
+			v[i] = nil
+			return v
+		}
+		v[i] = unsetValue(v[i], parts[1:])
+		return v
+	}
+	return container
+}
+
+// upsertSeed returns the document inserted by an upsert before the update is applied:
+// the equality conditions of filter
+func upsertSeed(filter bson.D) (bson.D, error) {
+	seed := bson.D{}
+	for _, e := range filter {
+		switch {
+		case e.Key == "$and":
+			clauses, _ := e.Value.(bson.A)
+			for _, clause := range clauses {
+				if clauseFilter, ok := clause.(bson.D); ok {
+					clauseSeed, err := upsertSeed(clauseFilter)
+					if err != nil {
+						return nil, err
+					}
+					for _, field := range clauseSeed {
+						if seed, err = setPath(seed, strings.Split(field.Key, "."), field.Value); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		case strings.HasPrefix(e.Key, "$"):
+		case isOperatorDocument(e.Value):
+			if value, ok := lookupField(e.Value.(bson.D), "$eq"); ok {
+				var err error
+				if seed, err = setPath(seed, strings.Split(e.Key, "."), value); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			var err error
+			if seed, err = setPath(seed, strings.Split(e.Key, "."), e.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return seed, nil
+}
@@ -0,0 +1,108 @@
+package mongoFake
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	doc := bson.D{
+		{Key: "_id", Value: int32(1)},
+		{Key: "name", Value: "alice"},
+		{Key: "count", Value: int32(2)},
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "address", Value: bson.D{{Key: "city", Value: "Hanoi"}}},
+	}
+
+	tests := []struct {
+		name      string
+		update    bson.M
+		inserting bool
+		want      bson.M
+	}{
+		{"$set", bson.M{"$set": bson.M{"name": "bob"}}, false, bson.M{"name": "bob"}},
+		{"$set nested", bson.M{"$set": bson.M{"address.zip": "10000"}}, false, bson.M{"address.zip": "10000", "address.city": "Hanoi"}},
+		{"$set creates path", bson.M{"$set": bson.M{"profile.age": 30}}, false, bson.M{"profile.age": 30}},
+		{"$unset", bson.M{"$unset": bson.M{"name": ""}}, false, bson.M{"name": bson.M{"$exists": false}}},
+		{"$inc", bson.M{"$inc": bson.M{"count": 3}}, false, bson.M{"count": 5}},
+		{"$inc missing", bson.M{"$inc": bson.M{"visits": 1}}, false, bson.M{"visits": 1}},
+		{"$min lower", bson.M{"$min": bson.M{"count": 1}}, false, bson.M{"count": 1}},
+		{"$min higher", bson.M{"$min": bson.M{"count": 9}}, false, bson.M{"count": 2}},
+		{"$max higher", bson.M{"$max": bson.M{"count": 9}}, false, bson.M{"count": 9}},
+		{"$push", bson.M{"$push": bson.M{"tags": "a"}}, false, bson.M{"tags": bson.A{"a", "b", "a"}}},
+		{"$push $each", bson.M{"$push": bson.M{"tags": bson.M{"$each": bson.A{"c", "d"}}}}, false, bson.M{"tags": bson.A{"a", "b", "c", "d"}}},
+		{"$addToSet", bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": bson.A{"b", "c"}}}}, false, bson.M{"tags": bson.A{"a", "b", "c"}}},
+		{"$pull", bson.M{"$pull": bson.M{"tags": "a"}}, false, bson.M{"tags": bson.A{"b"}}},
+		{"$pull condition", bson.M{"$pull": bson.M{"tags": bson.M{"$in": bson.A{"a", "b"}}}}, false, bson.M{"tags": bson.M{"$size": 0}}},
+		{"$setOnInsert on update", bson.M{"$setOnInsert": bson.M{"created": true}}, false, bson.M{"created": bson.M{"$exists": false}}},
+		{"$setOnInsert on insert", bson.M{"$setOnInsert": bson.M{"created": true}}, true, bson.M{"created": true}},
+		{"$currentDate", bson.M{"$currentDate": bson.M{"updatedAt": true}}, false, bson.M{"updatedAt": bson.M{"$type": "date"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := toDocument(tt.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := applyUpdate(doc, update, tt.inserting)
+			if err != nil {
+				t.Fatalf("applyUpdate returned %v", err)
+			}
+
+			if _, ok := tt.want["updatedAt"]; ok {
+				if _, ok := lookupField(got, "updatedAt"); !ok {
+					t.Errorf("applyUpdate(%v) = %v, missing updatedAt", tt.update, got)
+				}
+				return
+			}
+
+			want, err := toDocument(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := matches(got, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Errorf("applyUpdate(%v) = %v, want it to match %v", tt.update, got, tt.want)
+			}
+		})
+	}
+
+	if name, _ := lookupField(doc, "name"); name != "alice" {
+		t.Errorf("applyUpdate modified its input: %v", doc)
+	}
+}
+
+func TestApplyUpdateErrors(t *testing.T) {
+	doc := bson.D{{Key: "_id", Value: int32(1)}, {Key: "name", Value: "alice"}}
+
+	tests := []struct {
+		name   string
+		update bson.M
+	}{
+		{"replacement document", bson.M{"name": "bob"}},
+		{"unknown operator", bson.M{"$rename": bson.M{"name": "fullName"}}},
+		{"$inc on a string", bson.M{"$inc": bson.M{"name": 1}}},
+		{"$push on a string", bson.M{"$push": bson.M{"name": "x"}}},
+		{"changing _id", bson.M{"$set": bson.M{"_id": int32(2)}}},
+		{"positional operator", bson.M{"$set": bson.M{"tags.$": "x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := toDocument(tt.update)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := applyUpdate(doc, update, false); err == nil {
+				t.Errorf("applyUpdate(%v) returned no error", tt.update)
+			}
+		})
+	}
+}
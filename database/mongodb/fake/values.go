@@ -0,0 +1,269 @@
+package mongoFake
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDocument converts a record, filter or update to a bson.D holding the types stored by the driver
+// (int32, int64, float64, string, primitive.DateTime, bson.D, bson.A...), so values compare like on the server
+func toDocument(value any) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+	if v := reflect.ValueOf(value); (v.Kind() == reflect.Map || v.Kind() == reflect.Pointer) && v.IsNil() {
+		return bson.D{}, nil
+	}
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	return doc, bson.Unmarshal(data, &doc)
+}
+
+// toValue converts a single value the same way as toDocument
+func toValue(value any) (any, error) {
+	doc, err := toDocument(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return nil, err
+	}
+	return doc[0].Value, nil
+}
+
+// decodeDocuments decodes docs into records, a pointer to a slice
+func decodeDocuments(docs []bson.D, records any) error {
+	array := make(bson.A, len(docs))
+	for i, doc := range docs {
+		array[i] = doc
+	}
+
+	data, err := bson.Marshal(bson.D{{Key: "v", Value: array}})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("v").Unmarshal(records)
+}
+
+func decodeDocument(doc bson.D, record any) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, record)
+}
+
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case bson.D:
+		clone := make(bson.D, len(v))
+		for i, e := range v {
+			clone[i] = bson.E{Key: e.Key, Value: cloneValue(e.Value)}
+		}
+		return clone
+	case bson.A:
+		clone := make(bson.A, len(v))
+		for i, elem := range v {
+			clone[i] = cloneValue(elem)
+		}
+		return clone
+	}
+	return value
+}
+
+func cloneDocument(doc bson.D) bson.D {
+	return cloneValue(doc).(bson.D)
+}
+
+func lookupField(doc bson.D, key string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func isOperatorDocument(value any) bool {
+	doc, ok := value.(bson.D)
+	return ok && len(doc) > 0 && strings.HasPrefix(doc[0].Key, "$")
+}
+
+// resolve returns the values at path, traversing arrays of documents like the server does
+func resolve(value any, parts []string) []any {
+	if len(parts) == 0 {
+		return []any{value}
+	}
+
+	switch v := value.(type) {
+	case bson.D:
+		if child, ok := lookupField(v, parts[0]); ok {
+			return resolve(child, parts[1:])
+		}
+	case bson.A:
+		values := []any{}
+		if i, err := strconv.Atoi(parts[0]); err == nil {
+			if i >= 0 && i < len(v) {
+				values = append(values, resolve(v[i], parts[1:])...)
+			}
+		}
+		for _, elem := range v {
+			if _, ok := elem.(bson.D); ok {
+				values = append(values, resolve(elem, parts)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// candidates returns the values at path, plus the elements of the arrays found there
+func candidates(doc bson.D, path string) []any {
+	values := resolve(doc, strings.Split(path, "."))
+	expanded := slices.Clone(values)
+	for _, value := range values {
+		if array, ok := value.(bson.A); ok {
+			expanded = append(expanded, array...)
+		}
+	}
+	return expanded
+}
+
+// typeRank is the server sort order of BSON types
+func typeRank(value any) int {
+	switch value.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.MaxKey:
+		return 100
+	}
+	return 50
+}
+
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	}
+	return math.NaN()
+}
+
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// compareValues orders two values like the server: first by type, then by value
+func compareValues(a any, b any) int {
+	if rankA, rankB := typeRank(a), typeRank(b); rankA != rankB {
+		return cmp.Compare(rankA, rankB)
+	}
+
+	switch a := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		if intA, ok := toInt64(a); ok {
+			if intB, ok := toInt64(b); ok {
+				return cmp.Compare(intA, intB)
+			}
+		}
+		return cmp.Compare(toFloat(a), toFloat(b))
+	case string:
+		return strings.Compare(a, fmt.Sprint(b))
+	case primitive.Symbol:
+		return strings.Compare(string(a), fmt.Sprint(b))
+	case bson.D:
+		b := b.(bson.D)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := strings.Compare(a[i].Key, b[i].Key); c != 0 {
+				return c
+			}
+			if c := compareValues(a[i].Value, b[i].Value); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(a), len(b))
+	case bson.A:
+		b := b.(bson.A)
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := compareValues(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(a), len(b))
+	case primitive.Binary:
+		return bytes.Compare(a.Data, b.(primitive.Binary).Data)
+	case primitive.ObjectID:
+		id := b.(primitive.ObjectID)
+		return bytes.Compare(a[:], id[:])
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if a {
+			return 1
+		}
+		return -1
+	case primitive.DateTime:
+		return cmp.Compare(a, b.(primitive.DateTime))
+	case primitive.Timestamp:
+		return primitive.CompareTimestamp(a, b.(primitive.Timestamp))
+	case primitive.Regex:
+		return strings.Compare(a.String(), b.(primitive.Regex).String())
+	}
+	return 0
+}
+
+func equalValues(a any, b any) bool {
+	return typeRank(a) == typeRank(b) && compareValues(a, b) == 0
+}
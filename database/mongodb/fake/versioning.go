@@ -0,0 +1,87 @@
+package mongoFake
+
+import (
+	"errors"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	mongoWrites "github.com/BeeTechHub/go-common/database/mongodb/internal/writes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (c *Collection) UpdateOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.UpdateOptions) *bool { return opt.Upsert }); err != nil {
+		return nil, err
+	}
+
+	versioned, err := mongoWrites.VersionedUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.UpdateOne(sessContext, mongoWrites.VersionedFilter(filter, version), versioned, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return result, mongodb.ErrVersionConflict
+	}
+	return result, nil
+}
+
+func (c *Collection) ReplaceOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, updateRecord any, opts ...*options.ReplaceOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.ReplaceOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	doc, err := mongoWrites.VersionedRecord(updateRecord, version)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.replaceOne(mongoWrites.VersionedFilter(filter, version), doc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongodb.ErrVersionConflict
+	}
+	return nil
+}
+
+func (c *Collection) FindOneAndUpdateVersioned(sessContext mongo.SessionContext, record any, filter bson.M, version int64, update bson.M, opts ...*options.FindOneAndUpdateOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.FindOneAndUpdateOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	versioned, err := mongoWrites.VersionedUpdate(update)
+	if err != nil {
+		return err
+	}
+
+	err = c.FindOneAndUpdate(sessContext, record, mongoWrites.VersionedFilter(filter, version), versioned, opts...)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return mongodb.ErrVersionConflict
+	}
+	return err
+}
+
+func (c *Collection) FindOneAndReplaceVersioned(sessContext mongo.SessionContext, returnRecord any, filter bson.M, version int64, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.FindOneAndReplaceOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	doc, err := mongoWrites.VersionedRecord(updateRecord, version)
+	if err != nil {
+		return err
+	}
+
+	err = c.FindOneAndReplace(sessContext, returnRecord, mongoWrites.VersionedFilter(filter, version), doc, opts...)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return mongodb.ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package mongoFake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUpdateOneVersioned(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  bson.M
+		version int64
+		opts    []*options.UpdateOptions
+		wantErr error
+		want    int64
+	}{
+		{"matching version", bson.M{"_id": 1, "version": int64(3)}, 3, nil, nil, 4},
+		{"stale version", bson.M{"_id": 1, "version": int64(3)}, 2, nil, mongodb.ErrVersionConflict, 3},
+		{"missing field is version 0", bson.M{"_id": 1}, 0, nil, nil, 1},
+		{"missing field is not version 1", bson.M{"_id": 1}, 1, nil, mongodb.ErrVersionConflict, 0},
+		{"upsert is rejected", bson.M{"_id": 1, "version": int64(3)}, 3, []*options.UpdateOptions{options.Update().SetUpsert(true)}, mongodb.ErrVersionedUpsert, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollection("versioned")
+			if _, err := c.InsertOne(nil, tt.stored); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := c.UpdateOneVersioned(nil, bson.M{"_id": 1}, tt.version, bson.M{"$set": bson.M{"name": "x"}}, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			var stored struct {
+				Version int64 `bson:"version"`
+			}
+			if err := c.FindOne(nil, &stored, bson.M{"_id": 1}); err != nil {
+				t.Fatal(err)
+			}
+			if stored.Version != tt.want {
+				t.Errorf("version = %d, want %d", stored.Version, tt.want)
+			}
+		})
+	}
+}
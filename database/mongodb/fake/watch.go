@@ -0,0 +1,167 @@
+package mongoFake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// emit records a change event and wakes up the watchers. The caller holds c.mu.
+func (c *Collection) emit(operationType string, id any, fullDocument bson.D, description *mongodb.UpdateDescription) {
+	sequence := len(c.events) + 1
+	event := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: fmt.Sprintf("%016d", sequence)}}},
+		{Key: "operationType", Value: operationType},
+		{Key: "clusterTime", Value: primitive.Timestamp{T: uint32(time.Now().Unix()), I: uint32(sequence)}},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: id}}},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "mongoFake"}, {Key: "coll", Value: c.name}}},
+	}
+	if fullDocument != nil {
+		event = append(event, bson.E{Key: "fullDocument", Value: cloneDocument(fullDocument)})
+	}
+	if description != nil {
+		if value, err := toValue(description); err == nil {
+			event = append(event, bson.E{Key: "updateDescription", Value: value})
+		}
+	}
+
+	c.events = append(c.events, event)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// updateDescription lists the top-level fields changed between previous and doc
+func updateDescription(previous bson.D, doc bson.D) *mongodb.UpdateDescription {
+	description := &mongodb.UpdateDescription{UpdatedFields: bson.M{}, RemovedFields: []string{}}
+	for _, e := range doc {
+		if value, ok := lookupField(previous, e.Key); !ok || !equalValues(value, e.Value) {
+			description.UpdatedFields[e.Key] = e.Value
+		}
+	}
+	for _, e := range previous {
+		if !hasField(doc, e.Key) {
+			description.RemovedFields = append(description.RemovedFields, e.Key)
+		}
+	}
+	return description
+}
+
+func eventSequence(token bson.Raw) (int, error) {
+	data, ok := token.Lookup("_data").StringValueOK()
+	if !ok {
+		return 0, fmt.Errorf("mongoFake: invalid resume token %s", token)
+	}
+	return strconv.Atoi(data)
+}
+
+// next returns the event following sequence, or a channel closed when one is emitted
+func (c *Collection) next(sequence int) (bson.D, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sequence < len(c.events) {
+		return cloneDocument(c.events[sequence]), nil
+	}
+	return nil, c.changed
+}
+
+// Watch delivers the changes made to the collection after Watch is called, or after the token saved in opts.Store.
+// opts.Pipeline supports the stages of the aggregation methods. A handler error is passed to opts.OnError and
+// the event is delivered again after opts.ReconnectDelay (default 10ms).
+func (c *Collection) Watch(ctx context.Context, opts mongodb.WatchOptions, handler func(ctx context.Context, event mongodb.ChangeEvent[bson.Raw]) error) error {
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = 10 * time.Millisecond
+	}
+	lookup := opts.FullDocument == "" || opts.FullDocument == options.UpdateLookup ||
+		opts.FullDocument == options.WhenAvailable || opts.FullDocument == options.Required
+
+	pipeline, err := toValue(opts.Pipeline)
+	if err != nil {
+		return err
+	}
+	stages, _ := pipeline.(bson.A)
+
+	c.mu.Lock()
+	sequence := len(c.events)
+	c.mu.Unlock()
+
+	if opts.Store != nil {
+		token, err := opts.Store.Load(ctx, opts.Name)
+		if err != nil {
+			return err
+		}
+		if token != nil {
+			if sequence, err = eventSequence(token); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		event, changed := c.next(sequence)
+		if event == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+			continue
+		}
+
+		if operationType, _ := lookupField(event, "operationType"); operationType == "update" && !lookup {
+			event = unsetPath(event, []string{"fullDocument"})
+		}
+
+		output, err := aggregate([]bson.D{event}, stages)
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range output {
+			if err := c.deliver(ctx, opts, doc, handler); err != nil {
+				return err
+			}
+		}
+
+		sequence++
+		if opts.Store != nil {
+			token, err := bson.Marshal(bson.D{{Key: "_data", Value: fmt.Sprintf("%016d", sequence)}})
+			if err != nil {
+				return err
+			}
+			if err := opts.Store.Save(ctx, opts.Name, token); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// deliver calls handler until it succeeds or ctx is done
+func (c *Collection) deliver(ctx context.Context, opts mongodb.WatchOptions, doc bson.D, handler func(ctx context.Context, event mongodb.ChangeEvent[bson.Raw]) error) error {
+	var event mongodb.ChangeEvent[bson.Raw]
+	if err := decodeDocument(doc, &event); err != nil {
+		return err
+	}
+
+	for {
+		err := handler(ctx, event)
+		if err == nil {
+			return nil
+		}
+		if opts.OnError != nil {
+			opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.ReconnectDelay):
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package mongodb
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EnsureIndexesOptions configures EnsureIndexes
+type EnsureIndexesOptions struct {
+	// DropUnknown drops existing indexes that are not declared (the _id index is always kept)
+	DropUnknown bool
+	// DryRun only computes the report without changing indexes
+	DryRun bool
+}
+
+// IndexReport lists index names by the action EnsureIndexes took
+type IndexReport struct {
+	Created   []string
+	Recreated []string
+	Dropped   []string
+	Unchanged []string
+	// Unverified are existing indexes declared with options EnsureIndexes does not compare, e.g. text weights.
+	// They are kept as they are and should be checked by hand.
+	Unverified []string
+}
+
+type existingIndex struct {
+	Name                    string `bson:"name"`
+	Key                     bson.D `bson:"key"`
+	Unique                  bool   `bson:"unique"`
+	Sparse                  bool   `bson:"sparse"`
+	Hidden                  bool   `bson:"hidden"`
+	ExpireAfterSeconds      *int32 `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.D `bson:"partialFilterExpression"`
+	Collation               bson.D `bson:"collation"`
+}
+
+// indexName is the name the server generates for keys, e.g. "status_1_createdAt_-1"
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// declaredIndex describes model in the same shape as an index listed by the server
+func declaredIndex(model mongo.IndexModel) (existingIndex, error) {
+	keys, err := toDocument(model.Keys)
+	if err != nil {
+		return existingIndex{}, err
+	}
+
+	index := existingIndex{Name: indexName(keys), Key: keys}
+	if model.Options != nil {
+		if model.Options.Name != nil {
+			index.Name = *model.Options.Name
+		}
+		if model.Options.Unique != nil {
+			index.Unique = *model.Options.Unique
+		}
+		if model.Options.Sparse != nil {
+			index.Sparse = *model.Options.Sparse
+		}
+		if model.Options.Hidden != nil {
+			index.Hidden = *model.Options.Hidden
+		}
+		index.ExpireAfterSeconds = model.Options.ExpireAfterSeconds
+		if model.Options.PartialFilterExpression != nil {
+			if index.PartialFilterExpression, err = toDocument(model.Options.PartialFilterExpression); err != nil {
+				return existingIndex{}, err
+			}
+		}
+		if model.Options.Collation != nil {
+			if err := bson.Unmarshal(model.Options.Collation.ToDocument(), &index.Collation); err != nil {
+				return existingIndex{}, err
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// uncomparedOptions reports whether model sets options that sameAs does not compare
+func uncomparedOptions(model mongo.IndexModel) bool {
+	opts := model.Options
+	return opts != nil && (opts.StorageEngine != nil || opts.Version != nil || opts.DefaultLanguage != nil ||
+		opts.LanguageOverride != nil || opts.TextVersion != nil || opts.Weights != nil || opts.SphereVersion != nil ||
+		opts.Bits != nil || opts.Max != nil || opts.Min != nil || opts.BucketSize != nil || opts.WildcardProjection != nil)
+}
+
+// sameValue compares values as text, as the server may return 1 as int32 or double, and documents regardless of key order
+func sameValue(a any, b any) bool {
+	docA, okA := a.(bson.D)
+	docB, okB := b.(bson.D)
+	if okA != okB {
+		return false
+	} else if !okA {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	return len(docA) == len(docB) && containsFields(docA, docB)
+}
+
+// containsFields reports whether every field of subset has the same value in doc
+func containsFields(doc bson.D, subset bson.D) bool {
+	for _, e := range subset {
+		value, ok := lookupField(doc, e.Key)
+		if !ok || !sameValue(value, e.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameAs compares an existing index with the declared other
+func (index existingIndex) sameAs(other existingIndex) bool {
+	if len(index.Key) != len(other.Key) || index.Unique != other.Unique || index.Sparse != other.Sparse || index.Hidden != other.Hidden {
+		return false
+	}
+
+	if !sameValue(index.PartialFilterExpression, other.PartialFilterExpression) {
+		return false
+	}
+	// The server lists the collation with its defaults filled in, so only the declared fields are compared
+	if (index.Collation == nil) != (other.Collation == nil) || !containsFields(index.Collation, other.Collation) {
+		return false
+	}
+
+	for i := range index.Key {
+		// Compare values as text, the server may return 1 as int32 or double
+		if index.Key[i].Key != other.Key[i].Key || fmt.Sprint(index.Key[i].Value) != fmt.Sprint(other.Key[i].Value) {
+			return false
+		}
+	}
+
+	if (index.ExpireAfterSeconds == nil) != (other.ExpireAfterSeconds == nil) {
+		return false
+	}
+	return index.ExpireAfterSeconds == nil || *index.ExpireAfterSeconds == *other.ExpireAfterSeconds
+}
+
+// EnsureIndexes makes the indexes of the collection match models, compared by index name.
+// Missing indexes are created, and indexes whose keys or unique, sparse, hidden, TTL, partial filter or collation
+// options differ are dropped and recreated. Existing indexes declared with other options are reported Unverified.
+func (collection MongoCollectionWrapper) EnsureIndexes(sessContext mongo.SessionContext, models []mongo.IndexModel, opts ...EnsureIndexesOptions) (IndexReport, error) {
+	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
+	defer cancel()
+
+	var opt EnsureIndexesOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	report := IndexReport{}
+
+	cursor, err := collection.Collection.Indexes().List(ctx)
+	if err != nil {
+		return report, err
+	}
+	existingIndexes := []existingIndex{}
+	if err := cursor.All(ctx, &existingIndexes); err != nil {
+		return report, err
+	}
+
+	existingByName := make(map[string]existingIndex, len(existingIndexes))
+	for _, index := range existingIndexes {
+		existingByName[index.Name] = index
+	}
+
+	declaredNames := make(map[string]bool, len(models))
+	toCreate := []mongo.IndexModel{}
+	for _, model := range models {
+		declared, err := declaredIndex(model)
+		if err != nil {
+			return report, err
+		}
+		declaredNames[declared.Name] = true
+
+		existing, ok := existingByName[declared.Name]
+		if !ok {
+			report.Created = append(report.Created, declared.Name)
+		} else if existing.sameAs(declared) && uncomparedOptions(model) {
+			report.Unverified = append(report.Unverified, declared.Name)
+			continue
+		} else if existing.sameAs(declared) {
+			report.Unchanged = append(report.Unchanged, declared.Name)
+			continue
+		} else {
+			report.Recreated = append(report.Recreated, declared.Name)
+			if !opt.DryRun {
+				if _, err := collection.Collection.Indexes().DropOne(ctx, declared.Name); err != nil {
+					return report, err
+				}
+			}
+		}
+		toCreate = append(toCreate, model)
+	}
+
+	if opt.DropUnknown {
+		for _, index := range existingIndexes {
+			if index.Name == "_id_" || declaredNames[index.Name] {
+				continue
+			}
+			report.Dropped = append(report.Dropped, index.Name)
+			if !opt.DryRun {
+				if _, err := collection.Collection.Indexes().DropOne(ctx, index.Name); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+
+	if len(toCreate) > 0 && !opt.DryRun {
+		if _, err := collection.Collection.Indexes().CreateMany(ctx, toCreate); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
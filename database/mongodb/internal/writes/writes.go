@@ -0,0 +1,151 @@
+// Package mongoWrites builds the filters and updates of the writes shared by the mongodb wrapper and its fake
+package mongoWrites
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VersionField is the document field used for optimistic concurrency control
+const VersionField = "version"
+
+// ErrVersionedUpsert is returned when a versioned write is given the upsert option: on a conflict the versioned
+// filter matches nothing, so an upsert would insert a second document
+var ErrVersionedUpsert = errors.New("mongodb: versioned writes do not support upsert")
+
+// RejectUpsert returns ErrVersionedUpsert when the upsert option read from one of opts is set
+func RejectUpsert[T any](opts []*T, upsert func(opt *T) *bool) error {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if value := upsert(opt); value != nil && *value {
+			return ErrVersionedUpsert
+		}
+	}
+	return nil
+}
+
+// VersionedFilter adds the expected version to filter, without modifying filter. Version 0 also matches
+// documents without the field, e.g. written before versioning or with omitempty, whose first write sets it to 1.
+func VersionedFilter(filter bson.M, version int64) bson.M {
+	versioned := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		versioned[k] = v
+	}
+	versioned[VersionField] = version
+	if version == 0 {
+		versioned[VersionField] = bson.M{"$in": bson.A{int64(0), nil}}
+	}
+	return versioned
+}
+
+// VersionedUpdate adds a version increment to the $inc of update, without modifying update
+func VersionedUpdate(update bson.M) (bson.M, error) {
+	inc, err := toDocument(update["$inc"])
+	if err != nil {
+		return nil, err
+	}
+
+	versioned := make(bson.M, len(update)+1)
+	for k, v := range update {
+		versioned[k] = v
+	}
+	versioned["$inc"] = setField(inc, VersionField, 1)
+	return versioned, nil
+}
+
+// VersionedRecord converts record to a document holding the next version
+func VersionedRecord(record any, version int64) (bson.D, error) {
+	doc, err := toDocument(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return setField(doc, VersionField, version+1), nil
+}
+
+// IsZeroID reports whether an _id is the zero value of its type, e.g. an unset primitive.ObjectID field
+func IsZeroID(id any) bool {
+	switch id := id.(type) {
+	case nil:
+		return true
+	case primitive.ObjectID:
+		return id.IsZero()
+	case string:
+		return id == ""
+	}
+	return false
+}
+
+// UpsertModel splits record into a filter on its keyFields values and an update that sets its other fields,
+// inserting its _id unless it is zero so that the server generates one
+func UpsertModel(keyFields []string, record any) (bson.M, bson.M, error) {
+	doc, err := toDocument(record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := bson.M{}
+	for _, key := range keyFields {
+		value, ok := lookupField(doc, key)
+		if !ok {
+			return nil, nil, fmt.Errorf("mongodb: upsert key field %q is missing", key)
+		}
+		filter[key] = value
+	}
+
+	set, setOnInsert := bson.D{}, bson.D{}
+	for _, e := range doc {
+		switch {
+		case e.Key == "_id" && IsZeroID(e.Value):
+		case e.Key == "_id":
+			setOnInsert = append(setOnInsert, e)
+		default:
+			set = append(set, e)
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+	return filter, update, nil
+}
+
+func toDocument(value any) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	err = bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+func setField(doc bson.D, key string, value any) bson.D {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, bson.E{Key: key, Value: value})
+}
+
+func lookupField(doc bson.D, key string) (any, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,291 @@
+package mongodb
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultMigrationsCollection = "_migrations"
+const migrationLockID = "migration_lock"
+
+var ErrMigrationLocked = errors.New("mongodb: migrations are locked by another process")
+
+// Migration is one versioned schema change. Up and Down should be idempotent,
+// since a migration whose record failed to save is run again.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, database MongoDatabaseWrapper) error
+	// Down reverts Up, it may be nil if the migration cannot be reverted
+	Down func(ctx context.Context, database MongoDatabaseWrapper) error
+}
+
+// MigrationRecord is the document saved in the migrations collection for each applied migration
+type MigrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// MigratorOptions configures a Migrator. Zero values use the defaults.
+type MigratorOptions struct {
+	// Collection records applied migrations (default "_migrations"); the lock lives in Collection + "_lock"
+	Collection string
+	// LockTTL is how long the lock is held without renewal (default 1m), it is renewed while migrating
+	LockTTL time.Duration
+	// LockRetryInterval is the wait between attempts to take a held lock (default 2s)
+	LockRetryInterval time.Duration
+	// Timeout bounds each migration step (default 10m)
+	Timeout time.Duration
+}
+
+// Migrator runs migrations in version order against a database, one process at a time
+type Migrator struct {
+	database   MongoDatabaseWrapper
+	migrations []Migration
+	opts       MigratorOptions
+	owner      string
+}
+
+// NewMigrator sorts migrations by version and checks that versions are unique
+func (client MongoClientWrapper) NewMigrator(databaseName string, migrations []Migration, opts ...MigratorOptions) (*Migrator, error) {
+	var opt MigratorOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Collection == "" {
+		opt.Collection = defaultMigrationsCollection
+	}
+	if opt.LockTTL <= 0 {
+		opt.LockTTL = time.Minute
+	}
+	if opt.LockRetryInterval <= 0 {
+		opt.LockRetryInterval = 2 * time.Second
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = 10 * time.Minute
+	}
+
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i, migration := range sorted {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("mongodb: migration %d has no Up function", migration.Version)
+		}
+		if i > 0 && sorted[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("mongodb: duplicate migration version %d", migration.Version)
+		}
+	}
+
+	return &Migrator{
+		database:   client.GetDatabase(databaseName, opt.Timeout),
+		migrations: sorted,
+		opts:       opt,
+		owner:      newLockOwner(),
+	}, nil
+}
+
+func newLockOwner() string {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
+
+func (migrator *Migrator) records() MongoCollectionWrapper {
+	return migrator.database.GetCollection(migrator.opts.Collection)
+}
+
+func (migrator *Migrator) lockCollection() *mongo.Collection {
+	return migrator.database.Database.Collection(migrator.opts.Collection + "_lock")
+}
+
+// tryLock takes or renews the lock if it is free, expired, or already ours
+func (migrator *Migrator) tryLock(ctx context.Context) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": migrationLockID,
+		"$or": bson.A{
+			bson.M{"owner": migrator.owner},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": migrator.owner, "lockedUntil": now.Add(migrator.opts.LockTTL)}}
+
+	_, err := migrator.lockCollection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The lock document exists and is held by someone else, so the upsert collided with it
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (migrator *Migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), migrator.opts.LockTTL)
+	defer cancel()
+
+	_, _ = migrator.lockCollection().DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": migrator.owner})
+}
+
+// withLock waits for the lock, keeps renewing it while fn runs, and releases it afterwards
+func (migrator *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		locked, err := migrator.tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if locked {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(ErrMigrationLocked, ctx.Err())
+		case <-time.After(migrator.opts.LockRetryInterval):
+		}
+	}
+	defer migrator.unlock()
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(migrator.opts.LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lockCtx.Done():
+				return
+			case <-ticker.C:
+				if locked, err := migrator.tryLock(lockCtx); err == nil && !locked {
+					cancel(ErrMigrationLocked)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(lockCtx)
+	cancel(nil)
+	<-renewed
+
+	if cause := context.Cause(lockCtx); errors.Is(cause, ErrMigrationLocked) {
+		return errors.Join(cause, err)
+	}
+	return err
+}
+
+// Applied returns the records of applied migrations ordered by version
+func (migrator *Migrator) Applied(ctx context.Context) ([]MigrationRecord, error) {
+	records := []MigrationRecord{}
+	cursor, err := migrator.records().Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &records)
+	return records, err
+}
+
+func (migrator *Migrator) appliedVersions(ctx context.Context) (map[int64]bool, error) {
+	records, err := migrator.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+	return applied, nil
+}
+
+func (migrator *Migrator) runStep(ctx context.Context, step func(ctx context.Context, database MongoDatabaseWrapper) error) error {
+	stepCtx, cancel := context.WithTimeout(ctx, migrator.opts.Timeout)
+	defer cancel()
+
+	return step(stepCtx, migrator.database)
+}
+
+// Up applies every pending migration in version order
+func (migrator *Migrator) Up(ctx context.Context) error {
+	return migrator.UpTo(ctx, -1)
+}
+
+// UpTo applies pending migrations with a version up to target (a negative target applies all)
+func (migrator *Migrator) UpTo(ctx context.Context, target int64) error {
+	return migrator.withLock(ctx, func(ctx context.Context) error {
+		applied, err := migrator.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrator.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+			if target >= 0 && migration.Version > target {
+				break
+			}
+
+			if err := migrator.runStep(ctx, migration.Up); err != nil {
+				return fmt.Errorf("mongodb: migration %d up failed: %w", migration.Version, err)
+			}
+
+			record := MigrationRecord{migration.Version, migration.Description, time.Now()}
+			if _, err := migrator.records().Collection.InsertOne(ctx, record); err != nil {
+				return fmt.Errorf("mongodb: migration %d applied but not recorded: %w", migration.Version, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (migrator *Migrator) Down(ctx context.Context, steps int) error {
+	return migrator.withLock(ctx, func(ctx context.Context) error {
+		applied, err := migrator.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrator.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := migrator.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("mongodb: migration %d cannot be reverted, it has no Down function", migration.Version)
+			}
+
+			if err := migrator.runStep(ctx, migration.Down); err != nil {
+				return fmt.Errorf("mongodb: migration %d down failed: %w", migration.Version, err)
+			}
+
+			if _, err := migrator.records().Collection.DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+				return fmt.Errorf("mongodb: migration %d reverted but record not removed: %w", migration.Version, err)
+			}
+			steps--
+		}
+
+		return nil
+	})
+}
@@ -1,8 +1,6 @@
 package mongodb
 
 import (
-	"context"
-	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -21,39 +19,14 @@ type MongoDatabaseWrapper struct {
 type MongoCollectionWrapper struct {
 	Collection *mongo.Collection
 	Timeout    time.Duration
+
+	// audit is set by WithAudit
+	audit *auditConfig
 }
 
+// Deprecated: use Connect, which also supports pool, concern, TLS and monitoring options
 func ConnectDB(mongoUri string, timeOutConnection time.Duration) (MongoClientWrapper, error) {
-	/*cmdMonitor := &event.CommandMonitor{
-		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
-			log.Print(evt.Command)
-		},
-	}*/
-
-	fmt.Println("Try to connect to MongoDB:" + mongoUri)
-	//mongodbOption := options.Client().ApplyURI(EnvMongoURI()).SetTimeout(constants.TIME_OUT_CONNECTION * time.Second).SetMonitor(cmdMonitor)
-	mongodbOption := options.Client().ApplyURI(mongoUri).SetTimeout(timeOutConnection)
-	client, err := mongo.NewClient(mongodbOption)
-	if err != nil {
-		fmt.Println(err)
-		return MongoClientWrapper{client}, err
-	}
-
-	ctx, _ := context.WithTimeout(context.Background(), timeOutConnection)
-	err = client.Connect(ctx)
-	if err != nil {
-		fmt.Println(err)
-		return MongoClientWrapper{client}, err
-	}
-
-	//ping the database
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		fmt.Println(err)
-		return MongoClientWrapper{client}, err
-	}
-	fmt.Println("Connected to MongoDB")
-	return MongoClientWrapper{client}, nil
+	return Connect(ConnectOptions{Uri: mongoUri, Timeout: timeOutConnection})
 }
 
 func (client MongoClientWrapper) GetDatabase(databaseName string, timeout time.Duration) MongoDatabaseWrapper {
@@ -61,7 +34,7 @@ func (client MongoClientWrapper) GetDatabase(databaseName string, timeout time.D
 }
 
 func (database MongoDatabaseWrapper) GetCollection(collectionName string) MongoCollectionWrapper {
-	return MongoCollectionWrapper{database.Database.Collection(collectionName), database.Timeout}
+	return MongoCollectionWrapper{Collection: database.Database.Collection(collectionName), Timeout: database.Timeout}
 }
 
 func (client MongoClientWrapper) StartSession(opts ...*options.SessionOptions) (mongo.Session, error) {
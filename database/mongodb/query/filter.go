@@ -0,0 +1,167 @@
+package mongoQuery
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter builds a bson.M query filter:
+//
+//	filter, err := mongoQuery.NewFilter().For(schema).Eq("status", "active").Gte("createdAt", from).Build()
+//	collection.FindPaginated(nil, &records, page, size, filter, sortParams)
+type Filter struct {
+	schema *Schema
+	fields map[string]bson.M
+	order  []string
+	and    bson.A
+	errs   []error
+}
+
+func NewFilter() *Filter {
+	return &Filter{fields: map[string]bson.M{}}
+}
+
+// For validates the field names of the following operations against schema
+func (filter *Filter) For(schema *Schema) *Filter {
+	filter.schema = schema
+	return filter
+}
+
+func (filter *Filter) field(name string, operator string, value any) *Filter {
+	if err := filter.schema.Validate(name); err != nil {
+		filter.errs = append(filter.errs, err)
+		return filter
+	}
+
+	operators, ok := filter.fields[name]
+	if !ok {
+		operators = bson.M{}
+		filter.fields[name] = operators
+		filter.order = append(filter.order, name)
+	}
+
+	if _, exists := operators[operator]; exists {
+		filter.errs = append(filter.errs, fmt.Errorf("mongoQuery: %s is set twice on field %q", operator, name))
+		return filter
+	}
+	operators[operator] = value
+	return filter
+}
+
+func (filter *Filter) Eq(field string, value any) *Filter {
+	return filter.field(field, "$eq", value)
+}
+
+func (filter *Filter) Ne(field string, value any) *Filter {
+	return filter.field(field, "$ne", value)
+}
+
+func (filter *Filter) Gt(field string, value any) *Filter {
+	return filter.field(field, "$gt", value)
+}
+
+func (filter *Filter) Gte(field string, value any) *Filter {
+	return filter.field(field, "$gte", value)
+}
+
+func (filter *Filter) Lt(field string, value any) *Filter {
+	return filter.field(field, "$lt", value)
+}
+
+func (filter *Filter) Lte(field string, value any) *Filter {
+	return filter.field(field, "$lte", value)
+}
+
+func (filter *Filter) In(field string, values ...any) *Filter {
+	return filter.field(field, "$in", bson.A(values))
+}
+
+func (filter *Filter) Nin(field string, values ...any) *Filter {
+	return filter.field(field, "$nin", bson.A(values))
+}
+
+func (filter *Filter) Exists(field string, exists bool) *Filter {
+	return filter.field(field, "$exists", exists)
+}
+
+// Regex matches field against pattern, with regex options such as "i" for case-insensitive
+func (filter *Filter) Regex(field string, pattern string, options string) *Filter {
+	return filter.field(field, "$regex", primitive.Regex{Pattern: pattern, Options: options})
+}
+
+// ElemMatch matches arrays with at least one element matching every condition of elem.
+// Field names of elem are relative to the array element and are not validated against the parent schema.
+func (filter *Filter) ElemMatch(field string, elem *Filter) *Filter {
+	m, err := elem.Build()
+	if err != nil {
+		filter.errs = append(filter.errs, err)
+		return filter
+	}
+	return filter.field(field, "$elemMatch", m)
+}
+
+func (filter *Filter) combine(operator string, filters []*Filter) *Filter {
+	clauses := bson.A{}
+	for _, f := range filters {
+		m, err := f.Build()
+		if err != nil {
+			filter.errs = append(filter.errs, err)
+			continue
+		}
+		clauses = append(clauses, m)
+	}
+
+	filter.and = append(filter.and, bson.M{operator: clauses})
+	return filter
+}
+
+// And matches documents matching every filter
+func (filter *Filter) And(filters ...*Filter) *Filter {
+	return filter.combine("$and", filters)
+}
+
+// Or matches documents matching at least one filter
+func (filter *Filter) Or(filters ...*Filter) *Filter {
+	return filter.combine("$or", filters)
+}
+
+// Nor matches documents matching none of the filters
+func (filter *Filter) Nor(filters ...*Filter) *Filter {
+	return filter.combine("$nor", filters)
+}
+
+// Build returns the filter, or the errors of invalid field names and conflicting operators
+func (filter *Filter) Build() (bson.M, error) {
+	if len(filter.errs) > 0 {
+		return nil, errors.Join(filter.errs...)
+	}
+	return filter.M(), nil
+}
+
+// M returns the filter without reporting errors, use Build when validating against a schema
+func (filter *Filter) M() bson.M {
+	m := bson.M{}
+	for _, name := range filter.order {
+		operators := filter.fields[name]
+		if value, ok := operators["$eq"]; ok && len(operators) == 1 {
+			m[name] = value
+		} else {
+			m[name] = operators
+		}
+	}
+
+	switch len(filter.and) {
+	case 0:
+	case 1:
+		for k, v := range filter.and[0].(bson.M) {
+			m[k] = v
+		}
+	default:
+		m["$and"] = filter.and
+	}
+
+	return m
+}
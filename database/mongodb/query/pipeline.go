@@ -0,0 +1,130 @@
+package mongoQuery
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Pipeline builds the []bson.M stages accepted by FindManyWithAggregation, AggregatePaginated and the pipe of FindPaginated:
+//
+//	pipeline, err := mongoQuery.NewPipeline().
+//		Match(mongoQuery.NewFilter().For(schema).Eq("status", "paid")).
+//		Lookup("customers", "customerId", "_id", "customer").
+//		Unwind("customer", false).
+//		Build()
+type Pipeline struct {
+	stages []bson.M
+	errs   []error
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Stage appends a raw stage, for operators without a dedicated method
+func (pipeline *Pipeline) Stage(stage bson.M) *Pipeline {
+	pipeline.stages = append(pipeline.stages, stage)
+	return pipeline
+}
+
+func (pipeline *Pipeline) Match(filter *Filter) *Pipeline {
+	m, err := filter.Build()
+	if err != nil {
+		pipeline.errs = append(pipeline.errs, err)
+		return pipeline
+	}
+	return pipeline.Stage(bson.M{"$match": m})
+}
+
+// Lookup joins the documents of collection from whose foreignField equals localField, into the array field as
+func (pipeline *Pipeline) Lookup(from string, localField string, foreignField string, as string) *Pipeline {
+	return pipeline.Stage(bson.M{"$lookup": bson.M{
+		"from":         from,
+		"localField":   localField,
+		"foreignField": foreignField,
+		"as":           as,
+	}})
+}
+
+// LookupPipeline joins the output of sub, run on collection from, into the array field as.
+// let binds fields of the input document to variables usable in sub as "$$name".
+func (pipeline *Pipeline) LookupPipeline(from string, let bson.M, sub *Pipeline, as string) *Pipeline {
+	stages, err := sub.Build()
+	if err != nil {
+		pipeline.errs = append(pipeline.errs, err)
+		return pipeline
+	}
+
+	lookup := bson.M{
+		"from":     from,
+		"pipeline": stages,
+		"as":       as,
+	}
+	if len(let) > 0 {
+		lookup["let"] = let
+	}
+	return pipeline.Stage(bson.M{"$lookup": lookup})
+}
+
+// Unwind outputs one document per element of the array field path.
+// With preserveEmpty, documents whose array is missing or empty are kept.
+func (pipeline *Pipeline) Unwind(path string, preserveEmpty bool) *Pipeline {
+	return pipeline.Stage(bson.M{"$unwind": bson.M{
+		"path":                       "$" + path,
+		"preserveNullAndEmptyArrays": preserveEmpty,
+	}})
+}
+
+// Group groups documents by id, e.g. "$status" or bson.M{"status": "$status"}, computing the accumulators:
+//
+//	Group("$status", bson.M{"count": bson.M{"$sum": 1}, "amount": bson.M{"$sum": "$amount"}})
+func (pipeline *Pipeline) Group(id any, accumulators bson.M) *Pipeline {
+	group := bson.M{"_id": id}
+	for field, accumulator := range accumulators {
+		if field == "_id" {
+			pipeline.errs = append(pipeline.errs, errors.New("mongoQuery: $group accumulator cannot be named _id"))
+			continue
+		}
+		group[field] = accumulator
+	}
+	return pipeline.Stage(bson.M{"$group": group})
+}
+
+// Project includes (1), excludes (0) or computes the fields of the output documents
+func (pipeline *Pipeline) Project(projection bson.M) *Pipeline {
+	return pipeline.Stage(bson.M{"$project": projection})
+}
+
+func (pipeline *Pipeline) Sort(sort *Sort) *Pipeline {
+	d, err := sort.Build()
+	if err != nil {
+		pipeline.errs = append(pipeline.errs, err)
+		return pipeline
+	} else if len(d) == 0 {
+		pipeline.errs = append(pipeline.errs, errors.New("mongoQuery: empty $sort"))
+		return pipeline
+	}
+	return pipeline.Stage(bson.M{"$sort": d})
+}
+
+func (pipeline *Pipeline) Skip(skip int64) *Pipeline {
+	return pipeline.Stage(bson.M{"$skip": skip})
+}
+
+func (pipeline *Pipeline) Limit(limit int64) *Pipeline {
+	return pipeline.Stage(bson.M{"$limit": limit})
+}
+
+// Build returns the stages, or the errors of the filters and sorts given to the stages
+func (pipeline *Pipeline) Build() ([]bson.M, error) {
+	if len(pipeline.errs) > 0 {
+		return nil, errors.Join(pipeline.errs...)
+	}
+	return pipeline.Stages(), nil
+}
+
+// Stages returns the stages without reporting errors
+func (pipeline *Pipeline) Stages() []bson.M {
+	return append([]bson.M{}, pipeline.stages...)
+}
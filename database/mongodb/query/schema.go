@@ -0,0 +1,146 @@
+package mongoQuery
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema holds the field paths of a model, read from its bson tags, to validate builder field names
+type Schema struct {
+	name string
+	// fields maps each dotted path to true when any sub path is accepted under it (maps, interfaces)
+	fields map[string]bool
+}
+
+// SchemaOf builds the schema of model type T
+func SchemaOf[T any]() *Schema {
+	var model T
+	return NewSchema(model)
+}
+
+// NewSchema builds the schema of the struct type of model, which may be a pointer
+func NewSchema(model any) *Schema {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	schema := &Schema{fields: map[string]bool{}}
+	if t != nil {
+		schema.name = t.String()
+		schema.collect(t, "", map[reflect.Type]bool{})
+	}
+	return schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (schema *Schema) collect(t reflect.Type, prefix string, visiting map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Map || t.Kind() == reflect.Interface:
+		if prefix != "" {
+			schema.fields[prefix] = true
+		}
+		return
+	case t.Kind() != reflect.Struct || t == timeType || t.PkgPath() == "go.mongodb.org/mongo-driver/bson/primitive":
+		return
+	case visiting[t]:
+		// Recursive type, accept anything below
+		schema.fields[prefix] = true
+		return
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, inline := bsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		if inline {
+			schema.collect(field.Type, prefix, visiting)
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if _, ok := schema.fields[path]; !ok {
+			schema.fields[path] = false
+		}
+		schema.collect(field.Type, path, visiting)
+	}
+}
+
+// bsonFieldName follows the bson codec rules: the tag name, or the lowercased field name
+func bsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("bson")
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+
+	inline := false
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			inline = true
+		}
+	}
+
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, inline
+}
+
+// isArrayPathSegment reports whether segment is an array index or a positional operator ($, $[], $[id])
+func isArrayPathSegment(segment string) bool {
+	if _, err := strconv.Atoi(segment); err == nil {
+		return true
+	}
+	return segment == "$" || strings.HasPrefix(segment, "$[")
+}
+
+// Validate returns an error if path is not a field of the model.
+// Array indexes and positional operators are accepted between segments, e.g. "items.0.sku" or "items.$[].sku".
+func (schema *Schema) Validate(path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	current := ""
+	for _, segment := range strings.Split(path, ".") {
+		if isArrayPathSegment(segment) && current != "" {
+			continue
+		}
+
+		if current == "" {
+			current = segment
+		} else {
+			current = current + "." + segment
+		}
+
+		open, ok := schema.fields[current]
+		if !ok {
+			return fmt.Errorf("mongoQuery: unknown field %q in %s", path, schema.name)
+		}
+		if open {
+			return nil
+		}
+	}
+
+	return nil
+}
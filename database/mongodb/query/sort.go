@@ -0,0 +1,56 @@
+package mongoQuery
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Sort builds the ordered sort document accepted by FindPaginated and FindWithCursor:
+//
+//	sort, err := mongoQuery.NewSort().For(schema).Desc("createdAt").Asc("_id").Build()
+type Sort struct {
+	schema *Schema
+	fields bson.D
+	errs   []error
+}
+
+func NewSort() *Sort {
+	return &Sort{}
+}
+
+// For validates the field names of the following operations against schema
+func (sort *Sort) For(schema *Schema) *Sort {
+	sort.schema = schema
+	return sort
+}
+
+func (sort *Sort) field(name string, direction int) *Sort {
+	if err := sort.schema.Validate(name); err != nil {
+		sort.errs = append(sort.errs, err)
+		return sort
+	}
+	sort.fields = append(sort.fields, bson.E{Key: name, Value: direction})
+	return sort
+}
+
+func (sort *Sort) Asc(field string) *Sort {
+	return sort.field(field, 1)
+}
+
+func (sort *Sort) Desc(field string) *Sort {
+	return sort.field(field, -1)
+}
+
+// Build returns the sort, or the errors of invalid field names
+func (sort *Sort) Build() (bson.D, error) {
+	if len(sort.errs) > 0 {
+		return nil, errors.Join(sort.errs...)
+	}
+	return sort.D(), nil
+}
+
+// D returns the sort without reporting errors
+func (sort *Sort) D() bson.D {
+	return sort.fields
+}
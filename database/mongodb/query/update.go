@@ -0,0 +1,115 @@
+package mongoQuery
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Update builds a bson.M update document:
+//
+//	update, err := mongoQuery.NewUpdate().For(schema).Set("status", "paid").Inc("attempts", 1).Build()
+//	collection.UpdateOne(nil, filter, update)
+type Update struct {
+	schema    *Schema
+	operators map[string]bson.M
+	order     []string
+	errs      []error
+}
+
+func NewUpdate() *Update {
+	return &Update{operators: map[string]bson.M{}}
+}
+
+// For validates the field names of the following operations against schema
+func (update *Update) For(schema *Schema) *Update {
+	update.schema = schema
+	return update
+}
+
+func (update *Update) field(operator string, name string, value any) *Update {
+	if err := update.schema.Validate(name); err != nil {
+		update.errs = append(update.errs, err)
+		return update
+	}
+
+	fields, ok := update.operators[operator]
+	if !ok {
+		fields = bson.M{}
+		update.operators[operator] = fields
+		update.order = append(update.order, operator)
+	}
+
+	if _, exists := fields[name]; exists {
+		update.errs = append(update.errs, fmt.Errorf("mongoQuery: field %q is set twice in %s", name, operator))
+		return update
+	}
+	fields[name] = value
+	return update
+}
+
+func (update *Update) Set(field string, value any) *Update {
+	return update.field("$set", field, value)
+}
+
+// SetOnInsert sets field only when an upsert inserts a new document
+func (update *Update) SetOnInsert(field string, value any) *Update {
+	return update.field("$setOnInsert", field, value)
+}
+
+func (update *Update) Unset(field string) *Update {
+	return update.field("$unset", field, "")
+}
+
+func (update *Update) Inc(field string, value any) *Update {
+	return update.field("$inc", field, value)
+}
+
+// Push appends values to the array field
+func (update *Update) Push(field string, values ...any) *Update {
+	if len(values) == 1 {
+		return update.field("$push", field, values[0])
+	}
+	return update.field("$push", field, bson.M{"$each": bson.A(values)})
+}
+
+// AddToSet appends the values missing from the array field
+func (update *Update) AddToSet(field string, values ...any) *Update {
+	if len(values) == 1 {
+		return update.field("$addToSet", field, values[0])
+	}
+	return update.field("$addToSet", field, bson.M{"$each": bson.A(values)})
+}
+
+// Pull removes the array elements equal to value, or matching it when value is a condition such as a Filter
+func (update *Update) Pull(field string, value any) *Update {
+	if condition, ok := value.(*Filter); ok {
+		m, err := condition.Build()
+		if err != nil {
+			update.errs = append(update.errs, err)
+			return update
+		}
+		value = m
+	}
+	return update.field("$pull", field, value)
+}
+
+// Build returns the update, or the errors of invalid field names and conflicting operations
+func (update *Update) Build() (bson.M, error) {
+	if len(update.errs) > 0 {
+		return nil, errors.Join(update.errs...)
+	} else if len(update.order) == 0 {
+		return nil, errors.New("mongoQuery: empty update")
+	}
+	return update.M(), nil
+}
+
+// M returns the update without reporting errors, use Build when validating against a schema
+func (update *Update) M() bson.M {
+	m := bson.M{}
+	for _, operator := range update.order {
+		m[operator] = update.operators[operator]
+	}
+	return m
+}
@@ -0,0 +1,169 @@
+package mongodb
+
+import (
+	"errors"
+
+	"github.com/BeeTechHub/go-common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrNotFound = errors.New("mongodb: document not found")
+var ErrDuplicateKey = errors.New("mongodb: duplicate key")
+
+// Page is one page of records returned by Repository.FindPaginated
+type Page[T any] struct {
+	Items     []T
+	Page      int64
+	Size      int64
+	Total     int64
+	PageCount int64
+}
+
+// Repository is a typed view of a collection, decoding records into T
+type Repository[T any] struct {
+	Collection Collection
+}
+
+func NewRepository[T any](collection Collection) Repository[T] {
+	return Repository[T]{collection}
+}
+
+// translateError maps driver errors to the typed errors of this package
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.Join(ErrDuplicateKey, err)
+	}
+
+	return err
+}
+
+func (repository Repository[T]) FindOne(sessContext mongo.SessionContext, filter bson.M, opts ...*options.FindOneOptions) (T, error) {
+	var record T
+	err := repository.Collection.FindOne(sessContext, &record, filter, opts...)
+	return record, translateError(err)
+}
+
+func (repository Repository[T]) FindByID(sessContext mongo.SessionContext, id primitive.ObjectID) (T, error) {
+	var record T
+	err := repository.Collection.FindOneById(sessContext, &record, id)
+	return record, translateError(err)
+}
+
+func (repository Repository[T]) FindMany(sessContext mongo.SessionContext, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+	records := []T{}
+	err := repository.Collection.FindMany(sessContext, &records, filter, opts...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return records, nil
+}
+
+func (repository Repository[T]) FindPaginated(sessContext mongo.SessionContext, page int64, size int64, filter bson.M, sortParams bson.D, pipe ...bson.M) (Page[T], error) {
+	records := []T{}
+	total, err := repository.Collection.FindPaginated(sessContext, &records, page, size, filter, sortParams, pipe...)
+	if err != nil {
+		return Page[T]{}, translateError(err)
+	}
+
+	return newPage(records, page, size, total), nil
+}
+
+// AggregatePaginated returns one page of the output of pipeline, decoded into T, see MongoCollectionWrapper.AggregatePaginated
+func (repository Repository[T]) AggregatePaginated(sessContext mongo.SessionContext, page int64, size int64, pipeline []bson.M, sortParams bson.D, opts ...*options.AggregateOptions) (Page[T], error) {
+	records := []T{}
+	total, err := repository.Collection.AggregatePaginated(sessContext, &records, page, size, pipeline, sortParams, opts...)
+	if err != nil {
+		return Page[T]{}, translateError(err)
+	}
+
+	return newPage(records, page, size, total), nil
+}
+
+func newPage[T any](records []T, page int64, size int64, total int64) Page[T] {
+	pageCount := int64(0)
+	if size > 0 {
+		pageCount = utils.CalculatePageCount(total, size)
+	}
+
+	return Page[T]{
+		Items:     records,
+		Page:      page,
+		Size:      size,
+		Total:     total,
+		PageCount: pageCount,
+	}
+}
+
+func (repository Repository[T]) Count(sessContext mongo.SessionContext, filter bson.M, opts ...*options.CountOptions) (int64, error) {
+	count, err := repository.Collection.Count(sessContext, filter, opts...)
+	return count, translateError(err)
+}
+
+// Insert inserts record and returns its _id
+func (repository Repository[T]) Insert(sessContext mongo.SessionContext, record T, opts ...*options.InsertOneOptions) (interface{}, error) {
+	result, err := repository.Collection.InsertOne(sessContext, record, opts...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result.InsertedID, nil
+}
+
+// InsertMany inserts records and returns their _ids in order
+func (repository Repository[T]) InsertMany(sessContext mongo.SessionContext, records []T, opts ...*options.InsertManyOptions) ([]interface{}, error) {
+	if len(records) == 0 {
+		return []interface{}{}, nil
+	}
+
+	newRecords := make([]interface{}, len(records))
+	for i, record := range records {
+		newRecords[i] = record
+	}
+
+	result, err := repository.Collection.InsertMany(sessContext, newRecords, opts...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return result.InsertedIDs, nil
+}
+
+// UpdateByID applies update to the record with the given id, returning ErrNotFound if it does not exist
+func (repository Repository[T]) UpdateByID(sessContext mongo.SessionContext, id primitive.ObjectID, update bson.M, opts ...*options.UpdateOptions) error {
+	result, err := repository.Collection.UpdateOne(sessContext, bson.M{"_id": id}, update, opts...)
+	if err != nil {
+		return translateError(err)
+	}
+
+	if result.MatchedCount == 0 && result.UpsertedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteByID deletes the record with the given id, returning ErrNotFound if it does not exist
+func (repository Repository[T]) DeleteByID(sessContext mongo.SessionContext, id primitive.ObjectID) error {
+	result, err := repository.Collection.DeleteMany(sessContext, bson.M{"_id": id})
+	if err != nil {
+		return translateError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
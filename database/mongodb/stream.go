@@ -0,0 +1,133 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errStopIteration = errors.New("mongodb: iteration stopped")
+
+// WithTimeout returns a copy of the collection using timeout for calls without a session context.
+// Useful for slow queries, e.g. collection.WithTimeout(time.Minute).FindMany(...)
+func (collection MongoCollectionWrapper) WithTimeout(timeout time.Duration) MongoCollectionWrapper {
+	collection.Timeout = timeout
+	return collection
+}
+
+// eachDocument calls handle with every document of cursor, one at a time, until ctx is done or handle fails
+func eachDocument(ctx context.Context, cursor *mongo.Cursor, handle func(doc bson.Raw) error) error {
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(ctx) {
+		if err := handle(cursor.Current); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// streamCursor opens a cursor under the call timeout and iterates it without one, with sessContext when set,
+// so that a long stream is only ended by handle or the session
+func (collection MongoCollectionWrapper) streamCursor(sessContext mongo.SessionContext, open func(ctx context.Context) (*mongo.Cursor, error), handle func(doc bson.Raw) error) error {
+	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
+	cursor, err := open(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var iterCtx context.Context = context.Background()
+	if sessContext != nil {
+		iterCtx = sessContext
+	}
+	return eachDocument(iterCtx, cursor, handle)
+}
+
+// FindEach streams the documents matching filter to handle without loading them all in memory.
+// Without a session context, collection.Timeout bounds the query but not the iteration, which runs until
+// every document is handled or handle returns an error. doc is only valid until handle returns.
+// Use options.Find().SetBatchSize to tune the batch size.
+func (collection MongoCollectionWrapper) FindEach(sessContext mongo.SessionContext, filter bson.M, handle func(doc bson.Raw) error, opts ...*options.FindOptions) error {
+	return collection.streamCursor(sessContext, func(ctx context.Context) (*mongo.Cursor, error) {
+		return collection.Collection.Find(ctx, collection.scopeFilter(filter), opts...)
+	}, handle)
+}
+
+// AggregateEach streams the output of pipeline to handle without loading it all in memory.
+// Without a session context, collection.Timeout bounds the aggregation but not the iteration, which runs until
+// every document is handled or handle returns an error. doc is only valid until handle returns.
+// Use options.Aggregate().SetBatchSize to tune the batch size.
+func (collection MongoCollectionWrapper) AggregateEach(sessContext mongo.SessionContext, pipeline []bson.M, handle func(doc bson.Raw) error, opts ...*options.AggregateOptions) error {
+	return collection.streamCursor(sessContext, func(ctx context.Context) (*mongo.Cursor, error) {
+		return collection.Collection.Aggregate(ctx, collection.scopePipeline(pipeline), opts...)
+	}, handle)
+}
+
+// decodeSeq turns a callback stream into an iter.Seq2 decoding each document into T.
+// An error ends the sequence after being yielded.
+func decodeSeq[T any](stream func(handle func(doc bson.Raw) error) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		stopped := false
+		err := stream(func(doc bson.Raw) error {
+			var record T
+			if err := bson.Unmarshal(doc, &record); err != nil {
+				return err
+			}
+			if !yield(record, nil) {
+				stopped = true
+				return errStopIteration
+			}
+			return nil
+		})
+
+		if err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// FindSeq returns an iterator over the documents matching filter, decoded one at a time into T:
+//
+//	for record, err := range mongodb.FindSeq[Order](nil, collection, filter) { ... }
+func FindSeq[T any](sessContext mongo.SessionContext, collection Collection, filter bson.M, opts ...*options.FindOptions) iter.Seq2[T, error] {
+	return decodeSeq[T](func(handle func(doc bson.Raw) error) error {
+		return collection.FindEach(sessContext, filter, handle, opts...)
+	})
+}
+
+// AggregateSeq returns an iterator over the output of pipeline, decoded one at a time into T
+func AggregateSeq[T any](sessContext mongo.SessionContext, collection Collection, pipeline []bson.M, opts ...*options.AggregateOptions) iter.Seq2[T, error] {
+	return decodeSeq[T](func(handle func(doc bson.Raw) error) error {
+		return collection.AggregateEach(sessContext, pipeline, handle, opts...)
+	})
+}
+
+// FindEach streams the records matching filter to handle, decoded one at a time
+func (repository Repository[T]) FindEach(sessContext mongo.SessionContext, filter bson.M, handle func(record T) error, opts ...*options.FindOptions) error {
+	for record, err := range repository.FindSeq(sessContext, filter, opts...) {
+		if err != nil {
+			return translateError(err)
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindSeq returns an iterator over the records matching filter, decoded one at a time
+func (repository Repository[T]) FindSeq(sessContext mongo.SessionContext, filter bson.M, opts ...*options.FindOptions) iter.Seq2[T, error] {
+	return FindSeq[T](sessContext, repository.Collection, filter, opts...)
+}
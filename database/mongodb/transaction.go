@@ -0,0 +1,190 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/BeeTechHub/go-common/async"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+const defaultTransactionTimeout = 30 * time.Second
+const abortTransactionTimeout = 5 * time.Second
+
+const labelTransientTransactionError = "TransientTransactionError"
+const labelUnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+
+// TransactionOptions configures RunInTransaction. Zero values use the defaults.
+type TransactionOptions struct {
+	// ReadConcern defaults to majority
+	ReadConcern *readconcern.ReadConcern
+	// WriteConcern defaults to majority
+	WriteConcern *writeconcern.WriteConcern
+	// ReadPreference defaults to primary
+	ReadPreference *readpref.ReadPref
+	// Timeout bounds the whole call, retries included (default 30s)
+	Timeout time.Duration
+	// MaxCommitTime is the maximum time the server may spend on commitTransaction
+	MaxCommitTime *time.Duration
+	// MaxAttempts caps the number of times fn is run, and the number of commits of each run (0 means retry until Timeout)
+	MaxAttempts int
+}
+
+// transactionBackoff paces the retries of transactions and commits
+var transactionBackoff = async.RetryPolicy{InitialInterval: 10 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+
+// waitRetry sleeps the backoff of attempt, returning false if ctx is done first
+func waitRetry(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(transactionBackoff.Backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// canRetry reports whether another attempt is allowed by MaxAttempts and the deadline of ctx
+func (opts TransactionOptions) canRetry(ctx context.Context, attempt int) bool {
+	return ctx.Err() == nil && (opts.MaxAttempts <= 0 || attempt < opts.MaxAttempts) && waitRetry(ctx, attempt)
+}
+
+func (opts TransactionOptions) transactionOptions() *options.TransactionOptions {
+	txnOpts := options.Transaction().
+		SetReadConcern(readconcern.Majority()).
+		SetWriteConcern(writeconcern.Majority()).
+		SetReadPreference(readpref.Primary())
+
+	if opts.ReadConcern != nil {
+		txnOpts.SetReadConcern(opts.ReadConcern)
+	}
+	if opts.WriteConcern != nil {
+		txnOpts.SetWriteConcern(opts.WriteConcern)
+	}
+	if opts.ReadPreference != nil {
+		txnOpts.SetReadPreference(opts.ReadPreference)
+	}
+	if opts.MaxCommitTime != nil {
+		txnOpts.SetMaxCommitTime(opts.MaxCommitTime)
+	}
+
+	return txnOpts
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var labeled interface{ HasErrorLabel(string) bool }
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
+
+type afterCommitKey struct{}
+
+// afterCommitHooks are the functions registered by AfterCommit during one transaction attempt
+type afterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (hooks *afterCommitHooks) reset() {
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = nil
+}
+
+func (hooks *afterCommitHooks) run() {
+	hooks.mu.Lock()
+	registered := hooks.hooks
+	hooks.hooks = nil
+	hooks.mu.Unlock()
+
+	for _, hook := range registered {
+		hook()
+	}
+}
+
+// AfterCommit registers fn to run once the transaction of sessCtx is committed; it is dropped when the attempt aborts.
+// It returns false when sessCtx does not come from RunInTransaction, in which case fn is not registered.
+func AfterCommit(sessCtx mongo.SessionContext, fn func()) bool {
+	if sessCtx == nil {
+		return false
+	}
+	hooks, ok := sessCtx.Value(afterCommitKey{}).(*afterCommitHooks)
+	if !ok {
+		return false
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = append(hooks.hooks, fn)
+	return true
+}
+
+// RunInTransaction runs fn inside a transaction on a new session and commits it.
+// The whole transaction is retried on TransientTransactionError and the commit on UnknownTransactionCommitResult.
+// sessCtx can be passed as the sessContext parameter of every wrapper method, and to AfterCommit.
+func (client MongoClientWrapper) RunInTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error, opts ...TransactionOptions) error {
+	var opt TransactionOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = defaultTransactionTimeout
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	hooks := &afterCommitHooks{}
+	ctx = context.WithValue(ctx, afterCommitKey{}, hooks)
+
+	session, err := client.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	txnOpts := opt.transactionOptions()
+	for attempt := 1; ; attempt++ {
+		hooks.reset()
+		err = mongo.WithSession(ctx, session, func(sessCtx mongo.SessionContext) error {
+			if err := session.StartTransaction(txnOpts); err != nil {
+				return err
+			}
+
+			if err := fn(sessCtx); err != nil {
+				abortCtx, abortCancel := context.WithTimeout(context.Background(), abortTransactionTimeout)
+				defer abortCancel()
+				_ = session.AbortTransaction(abortCtx)
+				return err
+			}
+
+			for commitAttempt := 1; ; commitAttempt++ {
+				err := session.CommitTransaction(sessCtx)
+				if err == nil || !hasErrorLabel(err, labelUnknownTransactionCommitResult) || !opt.canRetry(ctx, commitAttempt) {
+					return err
+				}
+			}
+		})
+
+		if err == nil {
+			hooks.run()
+			return nil
+		}
+
+		if !hasErrorLabel(err, labelTransientTransactionError) || !opt.canRetry(ctx, attempt) {
+			return err
+		}
+	}
+}
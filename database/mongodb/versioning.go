@@ -0,0 +1,113 @@
+package mongodb
+
+import (
+	"errors"
+
+	mongoWrites "github.com/BeeTechHub/go-common/database/mongodb/internal/writes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VersionField is the document field used for optimistic concurrency control
+const VersionField = mongoWrites.VersionField
+
+var ErrVersionConflict = errors.New("mongodb: version conflict, document was modified or does not exist")
+
+// ErrVersionedUpsert is returned when a versioned write is given the upsert option: on a conflict the versioned
+// filter matches nothing, so an upsert would insert a second document
+var ErrVersionedUpsert = mongoWrites.ErrVersionedUpsert
+
+// UpdateOneVersioned applies update to the document matching filter only if its version equals version,
+// and increments the version. ErrVersionConflict is returned when no document matched.
+func (collection MongoCollectionWrapper) UpdateOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, update bson.M, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.UpdateOptions) *bool { return opt.Upsert }); err != nil {
+		return nil, err
+	}
+
+	versioned, err := mongoWrites.VersionedUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := collection.UpdateOne(sessContext, mongoWrites.VersionedFilter(filter, version), versioned, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return result, ErrVersionConflict
+	}
+
+	return result, nil
+}
+
+// ReplaceOneVersioned replaces the document matching filter only if its version equals version.
+// The stored record gets version+1. ErrVersionConflict is returned when no document matched.
+func (collection MongoCollectionWrapper) ReplaceOneVersioned(sessContext mongo.SessionContext, filter bson.M, version int64, updateRecord any, opts ...*options.ReplaceOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.ReplaceOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	ctx, cancel := getOrCreateContext(sessContext, collection.Timeout)
+	defer cancel()
+
+	doc, err := mongoWrites.VersionedRecord(updateRecord, version)
+	if err != nil {
+		return err
+	}
+
+	stampedRecord, err := collection.stampReplace(doc)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.Collection.ReplaceOne(ctx, collection.scopeFilter(mongoWrites.VersionedFilter(filter, version)), stampedRecord, opts...)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+// FindOneAndUpdateVersioned is UpdateOneVersioned returning the document in record
+func (collection MongoCollectionWrapper) FindOneAndUpdateVersioned(sessContext mongo.SessionContext, record any, filter bson.M, version int64, update bson.M, opts ...*options.FindOneAndUpdateOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.FindOneAndUpdateOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	versioned, err := mongoWrites.VersionedUpdate(update)
+	if err != nil {
+		return err
+	}
+
+	err = collection.FindOneAndUpdate(sessContext, record, mongoWrites.VersionedFilter(filter, version), versioned, opts...)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrVersionConflict
+	}
+
+	return err
+}
+
+// FindOneAndReplaceVersioned is ReplaceOneVersioned returning the document in returnRecord
+func (collection MongoCollectionWrapper) FindOneAndReplaceVersioned(sessContext mongo.SessionContext, returnRecord any, filter bson.M, version int64, updateRecord any, opts ...*options.FindOneAndReplaceOptions) error {
+	if err := mongoWrites.RejectUpsert(opts, func(opt *options.FindOneAndReplaceOptions) *bool { return opt.Upsert }); err != nil {
+		return err
+	}
+
+	doc, err := mongoWrites.VersionedRecord(updateRecord, version)
+	if err != nil {
+		return err
+	}
+
+	err = collection.FindOneAndReplace(sessContext, returnRecord, mongoWrites.VersionedFilter(filter, version), doc, opts...)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrVersionConflict
+	}
+
+	return err
+}
@@ -0,0 +1,302 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BeeTechHub/go-common/async"
+	awsSqs "github.com/BeeTechHub/go-common/aws/sqs"
+	"github.com/BeeTechHub/go-common/database/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusDispatched = "dispatched"
+	StatusFailed     = "failed"
+)
+
+var ErrNoTransaction = errors.New("outbox: events must be added inside a transaction session")
+
+// Event is a message waiting in the outbox collection to be published
+type Event struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Topic         string             `bson:"topic"`
+	Payload       string             `bson:"payload"`
+	Status        string             `bson:"status"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"lastError,omitempty"`
+	NextAttemptAt time.Time          `bson:"nextAttemptAt"`
+	LockedUntil   time.Time          `bson:"lockedUntil"`
+	LockedBy      string             `bson:"lockedBy,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt"`
+	DispatchedAt  *time.Time         `bson:"dispatchedAt,omitempty"`
+}
+
+// Outbox stores events in a collection, in the same transaction as the business write
+type Outbox struct {
+	Collection mongodb.Collection
+}
+
+func New(collection mongodb.Collection) Outbox {
+	return Outbox{collection}
+}
+
+// EnsureIndexes creates the index used by the relay to find due events
+func (outbox Outbox) EnsureIndexes() (mongodb.IndexReport, error) {
+	return outbox.Collection.EnsureIndexes(nil, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+	})
+}
+
+// Add inserts an event with sessCtx, which must belong to a transaction, e.g. inside MongoClientWrapper.RunInTransaction
+func (outbox Outbox) Add(sessCtx mongo.SessionContext, topic string, payload string) error {
+	if sessCtx == nil {
+		return ErrNoTransaction
+	}
+
+	now := time.Now()
+	event := Event{
+		Topic:         topic,
+		Payload:       payload,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	_, err := outbox.Collection.InsertOne(sessCtx, event)
+	return err
+}
+
+// AddJSON is Add with payload marshalled to JSON
+func (outbox Outbox) AddJSON(sessCtx mongo.SessionContext, topic string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Add(sessCtx, topic, string(data))
+}
+
+// Publisher sends an outbox event to a message broker
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Message attributes set by SqsPublisher, so consumers can route and deduplicate events
+const (
+	TopicAttribute   = "topic"
+	EventIDAttribute = "eventId"
+)
+
+// SqsPublisher sends the payload of every event to the queue of its topic in Queues, or else to Sqs
+type SqsPublisher struct {
+	Sqs    *awsSqs.SqsWrapper
+	Queues map[string]*awsSqs.SqsWrapper
+}
+
+func (publisher SqsPublisher) Publish(ctx context.Context, event Event) error {
+	queue := publisher.Sqs
+	if topicQueue, ok := publisher.Queues[event.Topic]; ok {
+		queue = topicQueue
+	}
+	if queue == nil {
+		return fmt.Errorf("outbox: no queue for topic %q", event.Topic)
+	}
+
+	_, err := queue.SendMsgWithContext(ctx, event.Payload, map[string]string{
+		TopicAttribute:   event.Topic,
+		EventIDAttribute: event.ID.Hex(),
+	})
+	return err
+}
+
+// RelayOptions configures a Relay. Zero values use the defaults.
+type RelayOptions struct {
+	// BatchSize is the maximum number of events dispatched per poll (default 50)
+	BatchSize int
+	// PollInterval is the wait between polls when the outbox is empty (default 1s)
+	PollInterval time.Duration
+	// LeaseDuration is how long a claimed event is hidden from other relays (default 30s)
+	LeaseDuration time.Duration
+	// RetryPolicy spaces out failed publishes; after MaxAttempts the event is marked failed (default 10 attempts, 1s..5m)
+	RetryPolicy async.RetryPolicy
+	// WatchInserts wakes the relay with a change stream on inserts instead of waiting for the next poll
+	WatchInserts bool
+	// OnError is called when an event fails to publish or the outbox cannot be read
+	OnError func(event *Event, err error)
+}
+
+// Relay publishes pending outbox events and marks them dispatched. Several relays can run concurrently.
+type Relay struct {
+	outbox    Outbox
+	publisher Publisher
+	opts      RelayOptions
+	owner     string
+}
+
+func NewRelay(outbox Outbox, publisher Publisher, opts RelayOptions) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = 30 * time.Second
+	}
+	if opts.RetryPolicy.MaxAttempts <= 0 {
+		opts.RetryPolicy = async.RetryPolicy{
+			MaxAttempts:     10,
+			InitialInterval: time.Second,
+			MaxInterval:     5 * time.Minute,
+			Multiplier:      2,
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		opts:      opts,
+		owner:     fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+func (relay *Relay) reportError(event *Event, err error) {
+	if relay.opts.OnError != nil {
+		relay.opts.OnError(event, err)
+	}
+}
+
+// Run dispatches events until ctx is done
+func (relay *Relay) Run(ctx context.Context) error {
+	wake := make(chan struct{}, 1)
+	if relay.opts.WatchInserts {
+		go func() {
+			watchOpts := mongodb.WatchOptions{
+				Pipeline:     []bson.M{{"$match": bson.M{"operationType": "insert"}}},
+				FullDocument: options.Default,
+				OnError:      func(err error) { relay.reportError(nil, err) },
+			}
+			_ = relay.outbox.Collection.Watch(ctx, watchOpts, func(ctx context.Context, event mongodb.ChangeEvent[bson.Raw]) error {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+				return nil
+			})
+		}()
+	}
+
+	for {
+		dispatched, err := relay.DispatchBatch(ctx)
+		if err != nil {
+			relay.reportError(nil, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// A full batch means more events are probably due
+		if dispatched >= relay.opts.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-time.After(relay.opts.PollInterval):
+		}
+	}
+}
+
+// DispatchBatch claims up to BatchSize due events, publishes them and records the outcome.
+// It returns the number of events claimed.
+func (relay *Relay) DispatchBatch(ctx context.Context) (int, error) {
+	claimed := 0
+	for claimed < relay.opts.BatchSize && ctx.Err() == nil {
+		event, err := relay.claim()
+		if err != nil {
+			return claimed, err
+		}
+		if event == nil {
+			break
+		}
+		claimed++
+
+		if err := relay.publisher.Publish(ctx, *event); err != nil {
+			relay.reportError(event, err)
+			if err := relay.markFailedAttempt(event, err); err != nil {
+				return claimed, err
+			}
+			continue
+		}
+
+		if err := relay.markDispatched(event); err != nil {
+			return claimed, err
+		}
+	}
+
+	return claimed, nil
+}
+
+// claim leases the oldest due event to this relay, returning nil when there is none
+func (relay *Relay) claim() (*Event, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":        StatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"lockedUntil":   bson.M{"$lt": now},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(relay.opts.LeaseDuration), "lockedBy": relay.owner}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}}).SetReturnDocument(options.After)
+
+	event := &Event{}
+	err := relay.outbox.Collection.FindOneAndUpdate(nil, event, filter, update, opts)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (relay *Relay) markDispatched(event *Event) error {
+	now := time.Now()
+	_, err := relay.outbox.Collection.UpdateOne(nil, bson.M{"_id": event.ID, "lockedBy": relay.owner}, bson.M{
+		"$set":   bson.M{"status": StatusDispatched, "dispatchedAt": now, "lockedUntil": time.Time{}},
+		"$unset": bson.M{"lastError": "", "lockedBy": ""},
+		"$inc":   bson.M{"attempts": 1},
+	})
+	return err
+}
+
+func (relay *Relay) markFailedAttempt(event *Event, publishErr error) error {
+	attempts := event.Attempts + 1
+	status := StatusPending
+	if attempts >= relay.opts.RetryPolicy.MaxAttempts {
+		status = StatusFailed
+	}
+
+	_, err := relay.outbox.Collection.UpdateOne(nil, bson.M{"_id": event.ID, "lockedBy": relay.owner}, bson.M{
+		"$set": bson.M{
+			"status":        status,
+			"attempts":      attempts,
+			"lastError":     publishErr.Error(),
+			"nextAttemptAt": time.Now().Add(relay.opts.RetryPolicy.Backoff(attempts)),
+			"lockedUntil":   time.Time{},
+		},
+		"$unset": bson.M{"lockedBy": ""},
+	})
+	return err
+}
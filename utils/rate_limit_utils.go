@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	awsRedis "github.com/BeeTechHub/go-common/aws/redis"
+	"github.com/BeeTechHub/go-common/logger"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimitConfig configures RateLimitMiddleware
+type RateLimitConfig struct {
+	Limiter *awsRedis.RateLimiter
+	// KeyFunc returns the key limited for a request (default the client IP), e.g. the user id for per-user limits
+	KeyFunc func(c *fiber.Ctx) string
+	// Next skips the limiter for the requests it returns true for
+	Next func(c *fiber.Ctx) bool
+	// LimitReached responds to rejected requests (default 429 Too Many Requests)
+	LimitReached fiber.Handler
+	// FailClosed rejects requests with 503 when redis fails, instead of letting them through
+	FailClosed bool
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimitMiddleware limits requests with config.Limiter and sets the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers, plus Retry-After on rejected requests
+func RateLimitMiddleware(config RateLimitConfig) fiber.Handler {
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(c *fiber.Ctx) string {
+			return c.IP()
+		}
+	}
+	if config.LimitReached == nil {
+		config.LimitReached = func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusTooManyRequests)
+		}
+	}
+
+	limit := config.Limiter.Limit()
+	policy := strconv.FormatInt(limit.Limit, 10) + ";w=" + ceilSeconds(limit.Window)
+
+	return func(c *fiber.Ctx) error {
+		if config.Next != nil && config.Next(c) {
+			return c.Next()
+		}
+
+		result, err := config.Limiter.Allow(c.UserContext(), config.KeyFunc(c))
+		if err != nil {
+			logger.Warnf("rate limit failed: %v", err)
+			if config.FailClosed {
+				return c.SendStatus(fiber.StatusServiceUnavailable)
+			}
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		c.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		c.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+		c.Set("RateLimit-Policy", policy)
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, ceilSeconds(result.RetryAfter))
+			return config.LimitReached(c)
+		}
+		return c.Next()
+	}
+}
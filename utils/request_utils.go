@@ -29,6 +29,25 @@ func GetPaginationParams(c *fiber.Ctx) (int64, int64, int64, int64) {
 	return page, size, firstIdx, lastIdx
 }
 
+// MaxCursorPageSize caps the size returned by GetCursorPaginationParams
+var MaxCursorPageSize int64 = 100
+
+// Reads the "cursor" and "size" query params used by keyset pagination, size is capped by MaxCursorPageSize
+func GetCursorPaginationParams(c *fiber.Ctx) (string, int64) {
+	cursor := c.Query("cursor", "")
+	sizeStr := c.Query("size", "10")
+
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil || size < 1 {
+		size = 10
+	}
+	if size > MaxCursorPageSize {
+		size = MaxCursorPageSize
+	}
+
+	return cursor, size
+}
+
 func GetSortParamsForMongoDb(c *fiber.Ctx) bson.D {
 	sortStr := c.Query("sort", "")
 